@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dependabot/cli/internal/server"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var apiSpecCmd = NewAPISpecCommand()
+
+func init() {
+	rootCmd.AddCommand(apiSpecCmd)
+}
+
+func NewAPISpecCommand() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "api-spec [flags]",
+		Short: "Print an OpenAPI specification of the update job API",
+		Long: heredoc.Doc(`
+			Print an OpenAPI 3 document describing the calls the updater makes to the
+			update job API, generated from the same types the CLI's fake API decodes
+			and validates the calls with.
+		`),
+		Example: heredoc.Doc(`
+		    $ dependabot api-spec > openapi.yml
+		    $ dependabot api-spec --json
+	    `),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec := server.Spec(Version())
+			if asJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(spec)
+			}
+			encoder := yaml.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent(2)
+			return encoder.Encode(spec)
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print JSON instead of YAML")
+
+	return cmd
+}
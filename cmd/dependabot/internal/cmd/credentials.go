@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+// addProviderCredentials inserts a git_source for the job's provider when its credentials are in the
+// environment, like $LOCAL_GITHUB_ACCESS_TOKEN does for GitHub.
+func addProviderCredentials(input *model.Input) {
+	source := &input.Job.Source
+	hostname := ""
+	if source.Hostname != nil {
+		hostname = *source.Hostname
+	}
+
+	switch source.Provider {
+	case "gitlab":
+		if os.Getenv("LOCAL_GITLAB_ACCESS_TOKEN") == "" {
+			return
+		}
+		// self-hosted GitLab has the hostname set
+		host := firstNonEmpty((&url.URL{Host: hostname}).Hostname(), "gitlab.com")
+		log.Printf("Inserting $LOCAL_GITLAB_ACCESS_TOKEN into credentials for %s\n", host)
+		addGitSource(input, model.Credential{
+			"type":     "git_source",
+			"host":     host,
+			"username": "x-access-token",
+			"password": "$LOCAL_GITLAB_ACCESS_TOKEN",
+		})
+	case "bitbucket":
+		cred := model.Credential{"type": "git_source", "host": "bitbucket.org"}
+		switch {
+		case os.Getenv("LOCAL_BITBUCKET_ACCESS_TOKEN") != "":
+			log.Println("Inserting $LOCAL_BITBUCKET_ACCESS_TOKEN into credentials")
+			cred["username"] = "x-token-auth"
+			cred["password"] = "$LOCAL_BITBUCKET_ACCESS_TOKEN"
+		case os.Getenv("LOCAL_BITBUCKET_USERNAME") != "" && os.Getenv("LOCAL_BITBUCKET_APP_PASSWORD") != "":
+			log.Println("Inserting $LOCAL_BITBUCKET_USERNAME and $LOCAL_BITBUCKET_APP_PASSWORD into credentials")
+			// expanded when the job runs, so the username isn't recorded in the scenario
+			cred["username"] = "$LOCAL_BITBUCKET_USERNAME"
+			cred["password"] = "$LOCAL_BITBUCKET_APP_PASSWORD"
+		default:
+			return
+		}
+		addGitSource(input, cred)
+	case "codecommit":
+		if os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
+			return
+		}
+		// the updater expects the region of a CodeCommit repository as its hostname
+		region := firstNonEmpty(hostname, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+		if region == "" {
+			log.Println("Not inserting AWS credentials, set $AWS_REGION to the region of the repository")
+			return
+		}
+		source.Hostname = &region
+		log.Printf("Inserting AWS credentials for CodeCommit in %s\n", region)
+		cred := model.Credential{
+			"type":     "git_source",
+			"host":     fmt.Sprintf("git-codecommit.%s.amazonaws.com", region),
+			"region":   region,
+			"username": "$AWS_ACCESS_KEY_ID",
+			"password": "$AWS_SECRET_ACCESS_KEY",
+		}
+		// temporary credentials, like those of SSO, don't work without their session token
+		if os.Getenv("AWS_SESSION_TOKEN") != "" {
+			cred["session-token"] = "$AWS_SESSION_TOKEN"
+		}
+		addGitSource(input, cred)
+	}
+}
+
+// githubHost is the host $LOCAL_GITHUB_ACCESS_TOKEN is for, github.com or a GitHub Enterprise Server.
+func githubHost(source *model.Source) string {
+	if (source.Provider == "" || source.Provider == "github") && source.Hostname != nil && *source.Hostname != "" {
+		return (&url.URL{Host: *source.Hostname}).Hostname()
+	}
+	return "github.com"
+}
+
+// addGitSource adds the credential, and its metadata when the job already has credentials-metadata since
+// it's only generated when empty.
+func addGitSource(input *model.Input, cred model.Credential) {
+	input.Credentials = append(input.Credentials, cred)
+	if len(input.Job.CredentialsMetadata) > 0 {
+		input.Job.CredentialsMetadata = append(input.Job.CredentialsMetadata, credentialMetadata(cred))
+	}
+}
+
+// credentialMetadata is the credential without its secrets, which the updater doesn't get.
+func credentialMetadata(cred model.Credential) model.Credential {
+	entry := model.Credential{}
+	for k, v := range cred {
+		if k != "token" && k != "password" && k != "key" && k != "auth-key" && k != "session-token" {
+			entry[k] = v
+		}
+	}
+	return entry
+}
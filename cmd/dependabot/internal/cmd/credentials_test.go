@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+func Test_addProviderCredentials(t *testing.T) {
+	selfHosted := "gitlab.example.com:8443"
+	tests := []struct {
+		name     string
+		env      map[string]string
+		source   model.Source
+		expected []model.Credential
+		hostname string
+	}{
+		{
+			name:   "gitlab.com",
+			env:    map[string]string{"LOCAL_GITLAB_ACCESS_TOKEN": "token"},
+			source: model.Source{Provider: "gitlab", Repo: "group/repo"},
+			expected: []model.Credential{{
+				"type": "git_source", "host": "gitlab.com", "username": "x-access-token", "password": "$LOCAL_GITLAB_ACCESS_TOKEN",
+			}},
+		},
+		{
+			name:   "self-hosted GitLab",
+			env:    map[string]string{"LOCAL_GITLAB_ACCESS_TOKEN": "token"},
+			source: model.Source{Provider: "gitlab", Repo: "group/sub/repo", Hostname: &selfHosted},
+			expected: []model.Credential{{
+				"type": "git_source", "host": "gitlab.example.com", "username": "x-access-token", "password": "$LOCAL_GITLAB_ACCESS_TOKEN",
+			}},
+			hostname: selfHosted,
+		},
+		{
+			name:   "Bitbucket access token",
+			env:    map[string]string{"LOCAL_BITBUCKET_ACCESS_TOKEN": "token"},
+			source: model.Source{Provider: "bitbucket", Repo: "workspace/repo"},
+			expected: []model.Credential{{
+				"type": "git_source", "host": "bitbucket.org", "username": "x-token-auth", "password": "$LOCAL_BITBUCKET_ACCESS_TOKEN",
+			}},
+		},
+		{
+			name:   "Bitbucket app password",
+			env:    map[string]string{"LOCAL_BITBUCKET_USERNAME": "me", "LOCAL_BITBUCKET_APP_PASSWORD": "secret"},
+			source: model.Source{Provider: "bitbucket", Repo: "workspace/repo"},
+			expected: []model.Credential{{
+				"type": "git_source", "host": "bitbucket.org", "username": "$LOCAL_BITBUCKET_USERNAME", "password": "$LOCAL_BITBUCKET_APP_PASSWORD",
+			}},
+		},
+		{
+			name:   "CodeCommit",
+			env:    map[string]string{"AWS_ACCESS_KEY_ID": "id", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "eu-west-1"},
+			source: model.Source{Provider: "codecommit", Repo: "repo"},
+			expected: []model.Credential{{
+				"type": "git_source", "host": "git-codecommit.eu-west-1.amazonaws.com", "region": "eu-west-1",
+				"username": "$AWS_ACCESS_KEY_ID", "password": "$AWS_SECRET_ACCESS_KEY",
+			}},
+			hostname: "eu-west-1",
+		},
+		{
+			name: "CodeCommit with temporary credentials",
+			env: map[string]string{
+				"AWS_ACCESS_KEY_ID": "id", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_SESSION_TOKEN": "session", "AWS_DEFAULT_REGION": "us-east-1",
+			},
+			source: model.Source{Provider: "codecommit", Repo: "repo"},
+			expected: []model.Credential{{
+				"type": "git_source", "host": "git-codecommit.us-east-1.amazonaws.com", "region": "us-east-1",
+				"username": "$AWS_ACCESS_KEY_ID", "password": "$AWS_SECRET_ACCESS_KEY", "session-token": "$AWS_SESSION_TOKEN",
+			}},
+			hostname: "us-east-1",
+		},
+		{
+			name:   "CodeCommit without a region",
+			env:    map[string]string{"AWS_ACCESS_KEY_ID": "id", "AWS_SECRET_ACCESS_KEY": "secret"},
+			source: model.Source{Provider: "codecommit", Repo: "repo"},
+		},
+		{
+			name:   "other provider",
+			env:    map[string]string{"LOCAL_GITLAB_ACCESS_TOKEN": "token"},
+			source: model.Source{Provider: "github", Repo: "org/repo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{
+				"LOCAL_GITLAB_ACCESS_TOKEN", "LOCAL_BITBUCKET_ACCESS_TOKEN", "LOCAL_BITBUCKET_USERNAME",
+				"LOCAL_BITBUCKET_APP_PASSWORD", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_REGION", "AWS_DEFAULT_REGION",
+			} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			input := &model.Input{Job: model.Job{Source: tt.source}}
+			addProviderCredentials(input)
+
+			if !reflect.DeepEqual(input.Credentials, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, input.Credentials)
+			}
+			var hostname string
+			if input.Job.Source.Hostname != nil {
+				hostname = *input.Job.Source.Hostname
+			}
+			if hostname != tt.hostname {
+				t.Errorf("expected hostname %q, got %q", tt.hostname, hostname)
+			}
+		})
+	}
+}
+
+func Test_addGitSource(t *testing.T) {
+	input := &model.Input{Job: model.Job{CredentialsMetadata: []model.Credential{{"type": "npm_registry"}}}}
+	addGitSource(input, model.Credential{"type": "git_source", "host": "gitlab.com", "username": "x-access-token", "password": "secret"})
+
+	expected := model.Credential{"type": "git_source", "host": "gitlab.com", "username": "x-access-token"}
+	if len(input.Job.CredentialsMetadata) != 2 || !reflect.DeepEqual(input.Job.CredentialsMetadata[1], expected) {
+		t.Errorf("expected the metadata without the password, got %v", input.Job.CredentialsMetadata)
+	}
+}
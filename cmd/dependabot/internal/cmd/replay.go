@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dependabot/cli/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = NewReplayCommand()
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+}
+
+type ReplayFlags struct {
+	to       string
+	jobID    string
+	token    string
+	files    []string
+	failFast bool
+}
+
+func NewReplayCommand() *cobra.Command {
+	var flags ReplayFlags
+
+	cmd := &cobra.Command{
+		Use:   "replay <scenario.yml> --to <url> [flags]",
+		Short: "Send the API calls recorded in a scenario to a job API",
+		Long: heredoc.Doc(`
+			Send the output of a scenario to a job API in order, the way the updater made
+			the calls, and report the responses. Updated files that were recorded as a
+			hash are sent with their original content when a matching copy is found in
+			one of the --files directories.
+		`),
+		Example: heredoc.Doc(`
+		    $ dependabot replay scenario.yml --to http://localhost:3000
+		    $ dependabot replay scenario.yml --to http://localhost:3000 --files ./checkout
+	    `),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.to == "" {
+				return errors.New("requires a job API URL to send the calls to")
+			}
+			scenario, _, err := readScenarioFile(args[0])
+			if err != nil {
+				return err
+			}
+			if len(scenario.Output) == 0 {
+				return fmt.Errorf("the scenario %s has no output to replay", args[0])
+			}
+
+			// the arguments are fine from here on, failed calls don't need the usage
+			cmd.SilenceUsage = true
+
+			replayer := &server.Replayer{
+				URL:   flags.to,
+				JobID: flags.jobID,
+				Token: firstNonEmpty(flags.token, os.Getenv("DEPENDABOT_JOB_TOKEN")),
+			}
+			if len(flags.files) > 0 {
+				replayer.Original = server.FilesFrom(flags.files)
+			}
+
+			out := cmd.OutOrStdout()
+			failed := 0
+			for i, output := range scenario.Output {
+				result := replayer.Send(context.Background(), output)
+				status := fmt.Sprint(result.Status)
+				if result.Err != nil {
+					status = result.Err.Error()
+				}
+				_, _ = fmt.Fprintf(out, "%d/%d %s: %s\n", i+1, len(scenario.Output), output.Type, status)
+				if result.Hashed > 0 {
+					_, _ = fmt.Fprintf(out, "    %d files were sent as a hash, pass --files to send their content\n", result.Hashed)
+				}
+				if result.OK() {
+					continue
+				}
+				failed++
+				if body := strings.TrimSpace(string(result.Body)); body != "" {
+					_, _ = fmt.Fprintf(out, "    %s\n", truncate(body, 500))
+				}
+				if flags.failFast {
+					break
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d calls failed", failed, len(scenario.Output))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.to, "to", "", "base URL of the job API to send the calls to")
+	cmd.Flags().StringVar(&flags.jobID, "job-id", firstNonEmpty(os.Getenv("DEPENDABOT_JOB_ID"), "cli"), "job ID in the call paths, defaults to $DEPENDABOT_JOB_ID")
+	cmd.Flags().StringVar(&flags.token, "token", "", "job token to authenticate with, defaults to $DEPENDABOT_JOB_TOKEN")
+	cmd.Flags().StringArrayVar(&flags.files, "files", nil, "directory with the updated files, like a checkout with the PR applied, to send instead of hashes")
+	cmd.Flags().BoolVar(&flags.failFast, "fail-fast", false, "stop at the first call that fails")
+
+	return cmd
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
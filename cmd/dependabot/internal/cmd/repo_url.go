@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+// repoURL is what the URL of a repository, as pasted from the browser, says about the job's source.
+type repoURL struct {
+	Provider string
+	// Hostname and APIEndpoint are only set for self-hosted providers
+	Hostname    string
+	APIEndpoint string
+	Repo        string
+	Branch      string
+	Directory   string
+}
+
+// publicHosts are the hosts a repository URL can be given for without the scheme.
+var publicHosts = map[string]string{
+	"github.com":    "github",
+	"gitlab.com":    "gitlab",
+	"bitbucket.org": "bitbucket",
+	"dev.azure.com": "azure",
+}
+
+// githubPages are the first segments after owner/repo of GitHub's pages, e.g. /org/repo/pull/5.
+var githubPages = map[string]bool{
+	"tree": true, "blob": true, "blame": true, "raw": true, "pull": true, "pulls": true, "issues": true,
+	"commit": true, "commits": true, "compare": true, "actions": true, "releases": true, "tags": true,
+	"branches": true, "wiki": true, "security": true, "settings": true, "projects": true, "discussions": true,
+	"pulse": true, "graphs": true, "network": true, "packages": true, "deployments": true, "activity": true,
+	"labels": true, "milestones": true, "find": true, "edit": true, "new": true, "archive": true, "checks": true,
+}
+
+var codecommitHost = regexp.MustCompile(`^git-codecommit\.([a-z0-9-]+)\.amazonaws\.com$`)
+
+// isRepoURL is whether the repo argument is a URL rather than a name like owner/repo.
+func isRepoURL(repo string) bool {
+	if strings.Contains(repo, "://") || strings.HasPrefix(repo, "git@") {
+		return true
+	}
+	host, _, _ := strings.Cut(repo, "/")
+	host = strings.ToLower(host)
+	_, ok := publicHosts[host]
+	return ok || strings.HasSuffix(host, ".visualstudio.com")
+}
+
+// parseRepoURL parses URLs of repositories and the directories in them, like
+// https://github.com/org/repo/tree/main/services/api or https://gitlab.example.com/group/sub/repo.
+// The provider of a self-hosted server is guessed from the URL unless it's given.
+func parseRepoURL(raw, provider string) (*repoURL, error) {
+	normalized := raw
+	if strings.HasPrefix(normalized, "git@") {
+		// git@host:path is an ssh remote
+		host, p, _ := strings.Cut(strings.TrimPrefix(normalized, "git@"), ":")
+		normalized = "https://" + host + "/" + p
+	} else if !strings.Contains(normalized, "://") {
+		normalized = "https://" + normalized
+	}
+	u, err := url.Parse(normalized)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid repository URL %q", raw)
+	}
+	host := strings.ToLower(u.Host)
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	// the hosted providers are known from the host, the others are guessed
+	known := publicHosts[host]
+	switch {
+	case strings.HasSuffix(host, ".visualstudio.com"):
+		known = "azure"
+	case codecommitHost.MatchString(host):
+		known = "codecommit"
+	}
+	switch {
+	case provider != "" && known != "" && provider != known:
+		return nil, fmt.Errorf("the repo URL is for %s, not %s", known, provider)
+	case provider == "" && known != "":
+		provider = known
+	case provider == "":
+		provider = guessProvider(host, segments)
+	}
+
+	switch provider {
+	case "azure":
+		return parseAzureURL(u)
+	case "codecommit":
+		if known != "codecommit" || len(segments) != 3 || segments[0] != "v1" || segments[1] != "repos" {
+			return nil, fmt.Errorf("expected a CodeCommit URL like https://git-codecommit.<region>.amazonaws.com/v1/repos/<repo>, got %q", raw)
+		}
+		return &repoURL{Provider: "codecommit", Hostname: codecommitHost.FindStringSubmatch(host)[1], Repo: segments[2]}, nil
+	case "bitbucket":
+		// only Bitbucket Cloud is supported by the updater
+		if known != "bitbucket" || len(segments) < 2 {
+			return nil, fmt.Errorf("expected a Bitbucket URL like https://bitbucket.org/<workspace>/<repo>, got %q", raw)
+		}
+		repo := &repoURL{Provider: "bitbucket", Repo: trimGit(segments[0] + "/" + segments[1])}
+		if len(segments) > 3 && segments[2] == "src" {
+			repo.Branch = segments[3]
+			repo.Directory = directoryOf(segments[4:], false)
+		}
+		return repo, nil
+	case "gitlab":
+		return parseGitLabURL(u, segments)
+	case "github":
+		return parseGitHubURL(u, segments)
+	}
+	return nil, fmt.Errorf("repository URLs aren't supported for the %s provider, pass the repo name", provider)
+}
+
+// guessProvider is the provider of a self-hosted server, which is GitHub unless the URL looks like GitLab.
+func guessProvider(host string, segments []string) string {
+	switch {
+	case contains(segments, "_git"):
+		return "azure"
+	case strings.Contains(host, "gitlab") || contains(segments, "-"):
+		return "gitlab"
+	case len(segments) > 2 && !githubPages[segments[2]]:
+		// GitHub repositories are always owner/repo, so more that isn't one of its pages is a self-hosted
+		// GitLab's subgroups
+		return "gitlab"
+	}
+	return "github"
+}
+
+func parseGitHubURL(u *url.URL, segments []string) (*repoURL, error) {
+	if len(segments) < 2 {
+		return nil, fmt.Errorf("expected a GitHub URL like https://%s/<owner>/<repo>, got %q", u.Host, u.String())
+	}
+	repo := &repoURL{Provider: "github", Repo: trimGit(segments[0] + "/" + segments[1])}
+	if !strings.EqualFold(u.Host, "github.com") {
+		repo.Hostname = webHost(u)
+		repo.APIEndpoint = fmt.Sprintf("%s://%s/api/v3/", apiScheme(u), repo.Hostname)
+	}
+	// the branch can't be told apart from the directory when it has a / in it, it's taken to be the first segment
+	switch {
+	case len(segments) > 3 && segments[2] == "tree":
+		repo.Branch = segments[3]
+		repo.Directory = directoryOf(segments[4:], false)
+	case len(segments) > 3 && (segments[2] == "blob" || segments[2] == "blame" || segments[2] == "raw"):
+		repo.Branch = segments[3]
+		repo.Directory = directoryOf(segments[4:], true)
+	}
+	return repo, nil
+}
+
+func parseGitLabURL(u *url.URL, segments []string) (*repoURL, error) {
+	// the repository's pages are under /-/, e.g. group/sub/repo/-/tree/main/dir
+	var pages []string
+	for i, segment := range segments {
+		if segment == "-" {
+			segments, pages = segments[:i], segments[i+1:]
+			break
+		}
+	}
+	if len(segments) < 2 {
+		return nil, fmt.Errorf("expected a GitLab URL like https://%s/<group>/<repo>, got %q", u.Host, u.String())
+	}
+	repo := &repoURL{Provider: "gitlab", Repo: trimGit(strings.Join(segments, "/"))}
+	if !strings.EqualFold(u.Host, "gitlab.com") {
+		repo.Hostname = webHost(u)
+		repo.APIEndpoint = fmt.Sprintf("%s://%s/api/v4", apiScheme(u), repo.Hostname)
+	}
+	if len(pages) > 1 && (pages[0] == "tree" || pages[0] == "blob") {
+		repo.Branch = pages[1]
+		repo.Directory = directoryOf(pages[2:], pages[0] == "blob")
+	}
+	return repo, nil
+}
+
+func parseAzureURL(u *url.URL) (*repoURL, error) {
+	query := u.Query()
+	withoutQuery := *u
+	withoutQuery.RawQuery = ""
+	withoutQuery.User = nil
+	azureRepo := model.NewAzureRepo("", withoutQuery.String(), "/")
+	if azureRepo == nil {
+		return nil, fmt.Errorf("expected an Azure DevOps URL like https://dev.azure.com/<org>/<project>/_git/<repo>, got %q", u.String())
+	}
+	repo := &repoURL{Provider: "azure", Repo: azureRepo.Path()}
+	if azureRepo.IsServer() {
+		repo.Hostname = azureRepo.Hostname
+		repo.APIEndpoint = azureRepo.APIEndpoint
+	}
+	// the branch and path being browsed are in the query, e.g. ?path=/src&version=GBmain
+	if version := query.Get("version"); strings.HasPrefix(version, "GB") {
+		repo.Branch = strings.TrimPrefix(version, "GB")
+	}
+	if dir := query.Get("path"); dir != "" {
+		repo.Directory = "/" + strings.Trim(dir, "/")
+	}
+	return repo, nil
+}
+
+// apiScheme is the scheme of the host's API, which is https unless the URL is http, e.g. for an ssh:// remote.
+func apiScheme(u *url.URL) string {
+	if strings.EqualFold(u.Scheme, "http") {
+		return "http"
+	}
+	return "https"
+}
+
+// webHost is the host of the web and API server, without the port of ssh:// remotes like ssh://git@host:2222.
+func webHost(u *url.URL) string {
+	if scheme := strings.ToLower(u.Scheme); scheme != "http" && scheme != "https" {
+		return u.Hostname()
+	}
+	return u.Host
+}
+
+// directoryOf returns the directory of the path segments, which are a file for blob URLs.
+func directoryOf(segments []string, isFile bool) string {
+	dir := "/" + strings.Join(segments, "/")
+	if isFile {
+		dir = path.Dir(dir)
+	}
+	if dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+func trimGit(repo string) string {
+	return strings.TrimSuffix(repo, ".git")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
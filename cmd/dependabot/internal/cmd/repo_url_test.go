@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func Test_parseRepoURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected repoURL
+	}{
+		{"https://github.com/org/repo", repoURL{Provider: "github", Repo: "org/repo"}},
+		{"github.com/org/repo.git", repoURL{Provider: "github", Repo: "org/repo"}},
+		{"git@github.com:org/repo.git", repoURL{Provider: "github", Repo: "org/repo"}},
+		{
+			"https://github.com/org/repo/tree/main/services/api",
+			repoURL{Provider: "github", Repo: "org/repo", Branch: "main", Directory: "/services/api"},
+		},
+		{
+			"https://github.com/org/repo/blob/main/services/api/go.mod",
+			repoURL{Provider: "github", Repo: "org/repo", Branch: "main", Directory: "/services/api"},
+		},
+		{"https://github.com/org/repo/tree/main", repoURL{Provider: "github", Repo: "org/repo", Branch: "main"}},
+		{
+			"https://ghes.example.com/org/repo/tree/release/docs",
+			repoURL{
+				Provider: "github", Hostname: "ghes.example.com", APIEndpoint: "https://ghes.example.com/api/v3/",
+				Repo: "org/repo", Branch: "release", Directory: "/docs",
+			},
+		},
+		{
+			"ssh://git@ghes.example.com:2222/org/repo.git",
+			repoURL{Provider: "github", Hostname: "ghes.example.com", APIEndpoint: "https://ghes.example.com/api/v3/", Repo: "org/repo"},
+		},
+		{
+			"git@gitlab.example.com:group/repo.git",
+			repoURL{Provider: "gitlab", Hostname: "gitlab.example.com", APIEndpoint: "https://gitlab.example.com/api/v4", Repo: "group/repo"},
+		},
+		{
+			"http://gitlab.internal/group/repo",
+			repoURL{Provider: "gitlab", Hostname: "gitlab.internal", APIEndpoint: "http://gitlab.internal/api/v4", Repo: "group/repo"},
+		},
+		{
+			"https://ghes.example.com/org/repo/pull/5",
+			repoURL{Provider: "github", Hostname: "ghes.example.com", APIEndpoint: "https://ghes.example.com/api/v3/", Repo: "org/repo"},
+		},
+		{
+			"https://ghes.example.com/org/repo/commit/832e37c1a7a4ef89feb9dc7cfa06f62205191994",
+			repoURL{Provider: "github", Hostname: "ghes.example.com", APIEndpoint: "https://ghes.example.com/api/v3/", Repo: "org/repo"},
+		},
+		{"https://github.com/org/repo/issues?q=is%3Aopen", repoURL{Provider: "github", Repo: "org/repo"}},
+		{
+			"https://ghes.example.com/org/repo/blame/main/api/go.mod",
+			repoURL{
+				Provider: "github", Hostname: "ghes.example.com", APIEndpoint: "https://ghes.example.com/api/v3/",
+				Repo: "org/repo", Branch: "main", Directory: "/api",
+			},
+		},
+		// a branch with a / in it reads as a branch and a directory
+		{
+			"https://github.com/org/repo/tree/feature/login/api",
+			repoURL{Provider: "github", Repo: "org/repo", Branch: "feature", Directory: "/login/api"},
+		},
+		{"https://gitlab.com/group/sub/repo", repoURL{Provider: "gitlab", Repo: "group/sub/repo"}},
+		{
+			"https://gitlab.example.com/group/sub/repo",
+			repoURL{Provider: "gitlab", Hostname: "gitlab.example.com", APIEndpoint: "https://gitlab.example.com/api/v4", Repo: "group/sub/repo"},
+		},
+		{
+			"https://code.example.com:8443/group/repo/-/tree/main/api",
+			repoURL{
+				Provider: "gitlab", Hostname: "code.example.com:8443", APIEndpoint: "https://code.example.com:8443/api/v4",
+				Repo: "group/repo", Branch: "main", Directory: "/api",
+			},
+		},
+		{
+			"https://bitbucket.org/workspace/repo/src/main/api/",
+			repoURL{Provider: "bitbucket", Repo: "workspace/repo", Branch: "main", Directory: "/api"},
+		},
+		{
+			"https://dev.azure.com/org/project/_git/repo?path=/api&version=GBmain",
+			repoURL{Provider: "azure", Repo: "org/project/_git/repo", Branch: "main", Directory: "/api"},
+		},
+		{
+			"https://tfs.example.com/tfs/collection/project/_git/repo",
+			repoURL{
+				Provider: "azure", Hostname: "tfs.example.com", APIEndpoint: "https://tfs.example.com/tfs/",
+				Repo: "collection/project/_git/repo",
+			},
+		},
+		{
+			"https://git-codecommit.eu-west-1.amazonaws.com/v1/repos/repo",
+			repoURL{Provider: "codecommit", Hostname: "eu-west-1", Repo: "repo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if !isRepoURL(tt.url) {
+				t.Fatalf("expected %v to be a URL", tt.url)
+			}
+			actual, err := parseRepoURL(tt.url, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *actual != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, *actual)
+			}
+		})
+	}
+
+	for _, repo := range []string{"org/repo", "my.group/repo", "org/project/_git/repo"} {
+		if isRepoURL(repo) {
+			t.Errorf("expected %v not to be a URL", repo)
+		}
+	}
+	for _, url := range []string{"https://github.com/org", "https://dev.azure.com/org/project", "https://git-codecommit.us-east-1.amazonaws.com/repo"} {
+		if _, err := parseRepoURL(url, ""); err == nil {
+			t.Errorf("expected an error for %v", url)
+		}
+	}
+}
+
+func Test_parseRepoURL_provider(t *testing.T) {
+	// a self-hosted GitLab that would be guessed to be GitHub
+	actual, err := parseRepoURL("https://git.example.com/group/repo/-/tree/main/api", "gitlab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := repoURL{
+		Provider: "gitlab", Hostname: "git.example.com", APIEndpoint: "https://git.example.com/api/v4",
+		Repo: "group/repo", Branch: "main", Directory: "/api",
+	}
+	if *actual != expected {
+		t.Errorf("expected %+v, got %+v", expected, *actual)
+	}
+	if actual, err = parseRepoURL("https://git.example.com/group/repo", "gitlab"); err != nil || actual.Provider != "gitlab" {
+		t.Errorf("expected a GitLab repo, got %+v %v", actual, err)
+	}
+
+	// the hosted providers are known from the host
+	for url, provider := range map[string]string{
+		"https://github.com/org/repo":           "gitlab",
+		"https://gitlab.com/group/repo":         "github",
+		"https://bitbucket.example.com/ws/repo": "bitbucket",
+		"https://git.example.com/repo":          "go_modules",
+	} {
+		if _, err := parseRepoURL(url, provider); err == nil {
+			t.Errorf("expected an error for %v with %v", url, provider)
+		}
+	}
+}
@@ -19,10 +19,16 @@ type SharedFlags struct {
 	collectorConfigPath string
 	extraHosts          []string
 	output              string
-	pullImages          bool
+	pullPolicy          infra.PullPolicy
 	volumes             []string
 	timeout             time.Duration
 	local               string
+	preserveGit         bool
+	localExclude        []string
+	gitRepo             string
+	notifyURLs          []string
+	notifySecret        string
+	pinImages           bool
 }
 
 // root flags
@@ -44,6 +50,26 @@ var rootCmd = &cobra.Command{
 	Version: Version(),
 }
 
+// addPullFlag adds the --pull flag. A bare --pull, and --pull=true, keep working as they did when the
+// flag was a boolean.
+func addPullFlag(cmd *cobra.Command, policy *infra.PullPolicy) {
+	cmd.Flags().Var(policy, "pull", "when to pull images: always, missing, never, or newer-than=<duration>")
+	cmd.Flags().Lookup("pull").NoOptDefVal = infra.PullMissing
+}
+
+// addNotifyFlags adds the flags for notifying webhooks at the end of a run. The secret can come from the
+// environment so it doesn't show up in the process list.
+func addNotifyFlags(cmd *cobra.Command, flags *SharedFlags) {
+	cmd.Flags().StringArrayVar(&flags.notifyURLs, "notify-url", nil, "URL to POST a JSON summary of the run to")
+	cmd.Flags().StringVar(&flags.notifySecret, "notify-secret", "", "secret to sign notifications with, defaults to $DEPENDABOT_NOTIFY_SECRET")
+}
+
+// notifySecret is the --notify-secret flag, or $DEPENDABOT_NOTIFY_SECRET when it isn't set. The environment
+// isn't the flag's default so that --help doesn't print the secret.
+func notifySecret(flag string) string {
+	return firstNonEmpty(flag, os.Getenv("DEPENDABOT_NOTIFY_SECRET"))
+}
+
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
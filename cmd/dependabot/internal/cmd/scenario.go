@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dependabot/cli/internal/infra"
+	"github.com/spf13/cobra"
+)
+
+var scenarioCmd = NewScenarioCommand()
+
+func init() {
+	rootCmd.AddCommand(scenarioCmd)
+}
+
+func NewScenarioCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scenario",
+		Short: "Work with scenario files",
+	}
+	cmd.AddCommand(NewScenarioRedactCommand())
+	return cmd
+}
+
+type ScenarioRedactFlags struct {
+	output       string
+	mapping      string
+	dependencies []string
+	hosts        []string
+}
+
+func NewScenarioRedactCommand() *cobra.Command {
+	var flags ScenarioRedactFlags
+
+	cmd := &cobra.Command{
+		Use:   "redact <scenario.yml> [flags]",
+		Short: "Remove credentials and internal names from a scenario so it can be shared",
+		Long: heredoc.Doc(`
+			Rewrite a scenario without its credentials, replacing the hosts of the source and
+			registries, the repository wherever it appears as owner/repo, and the dependencies
+			given with --dependency with pseudonyms like host-1.example.com, owner-1/repo-1 and
+			dependency-1, consistently across the input and output.
+
+			The pseudonyms are written to a mapping file in the user's config directory, away
+			from the scenarios so it isn't committed with them by accident. It's reused when it
+			exists so several scenarios are redacted consistently. Keep it local, it holds the
+			names that were redacted.
+		`),
+		Example: heredoc.Doc(`
+		    $ dependabot scenario redact scenario.yml -o shared.yml
+		    $ dependabot scenario redact scenario.yml --dependency @acme/widgets --host git.acme.internal
+	    `),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file := args[0]
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to open scenario file: %w", err)
+			}
+
+			mapping := flags.mapping
+			if mapping == "" {
+				if mapping, err = defaultMappingFile(); err != nil {
+					return err
+				}
+			}
+			pseudonyms, err := infra.LoadPseudonyms(mapping)
+			if err != nil {
+				return err
+			}
+
+			redacted, err := infra.RedactScenario(data, pseudonyms, flags.dependencies, flags.hosts)
+			if err != nil {
+				return err
+			}
+			if err = pseudonyms.Save(mapping); err != nil {
+				return err
+			}
+			log.Printf("Wrote the pseudonyms to %s, don't share it\n", mapping)
+
+			if flags.output == "" {
+				_, err = cmd.OutOrStdout().Write(redacted)
+				return err
+			}
+			if err = os.WriteFile(flags.output, redacted, 0o644); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "write the redacted scenario to a file instead of stdout")
+	cmd.Flags().StringVar(&flags.mapping, "mapping", "", "file with the pseudonyms, defaults to dependabot/pseudonyms.yml in the user's config directory")
+	cmd.Flags().StringArrayVar(&flags.dependencies, "dependency", nil, "name of a dependency to replace with a pseudonym")
+	cmd.Flags().StringArrayVar(&flags.hosts, "host", nil, "host to replace with a pseudonym, in addition to the source and credential hosts")
+
+	return cmd
+}
+
+// defaultMappingFile is the mapping file in the user's config directory, which is created if needed.
+func defaultMappingFile() (string, error) {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("pass --mapping, there's no config directory for the mapping file: %w", err)
+	}
+	dir := filepath.Join(config, "dependabot")
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	return filepath.Join(dir, "pseudonyms.yml"), nil
+}
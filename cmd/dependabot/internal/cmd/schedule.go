@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dependabot/cli/internal/model"
+	"github.com/dependabot/cli/internal/queue"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var scheduleCmd = NewScheduleCommand()
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+}
+
+type ScheduleFlags struct {
+	SharedFlags
+	dir         string
+	concurrency int
+	runNow      bool
+}
+
+// scheduleConfig is the file given to the schedule command.
+type scheduleConfig struct {
+	// Keep is the default number of runs kept per job
+	Keep int             `yaml:"keep"`
+	Jobs []scheduleEntry `yaml:"jobs"`
+}
+
+type scheduleEntry struct {
+	Name     string `yaml:"name"`
+	Schedule string `yaml:"schedule"`
+	Keep     int    `yaml:"keep"`
+	// File is an input file, relative to the config file
+	File string `yaml:"file"`
+	// Job and Credentials can be used instead of a separate input file
+	Job         *model.Job         `yaml:"job"`
+	Credentials []model.Credential `yaml:"credentials"`
+}
+
+const defaultKeep = 10
+
+func NewScheduleCommand() *cobra.Command {
+	var flags ScheduleFlags
+
+	cmd := &cobra.Command{
+		Use:   "schedule -f <schedule.yml> [flags]",
+		Short: "Run update jobs on a schedule",
+		Long: heredoc.Doc(`
+			Run the update jobs listed in a schedule file whenever they are due. Each job has
+			a cron expression (e.g. "30 2 * * 1-5"), a macro like @daily, or an interval like
+			"@every 6h". The last runs of each job are kept in the jobs directory, along with
+			a summary.yml of their results.
+		`),
+		Example: heredoc.Doc(`
+		    $ dependabot schedule -f schedule.yml
+		    $ dependabot schedule -f schedule.yml --run-now
+	    `),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.file == "" {
+				return errors.New("requires a schedule file")
+			}
+			jobs, err := readScheduleFile(flags.file)
+			if err != nil {
+				return err
+			}
+
+			q, err := queue.New(flags.dir, flags.concurrency, func(input *model.Input, logs io.Writer, output string) error {
+				return runQueuedJob(input, logs, output, &flags.SharedFlags, nil)
+			})
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			log.Printf("Scheduling %d jobs, results are in %s\n", len(jobs), flags.dir)
+			queue.NewScheduler(q, jobs).Run(ctx, flags.runNow)
+			log.Println("Shutting down, waiting for running jobs to stop")
+			q.Close()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.file, "file", "f", "", "path to the schedule file")
+	cmd.Flags().StringVar(&flags.dir, "dir", "dependabot-schedule", "directory to keep the results in")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", 1, "maximum number of jobs to run at once")
+	cmd.Flags().BoolVar(&flags.runNow, "run-now", false, "run every job once at startup, then follow the schedule")
+
+	cmd.Flags().StringVar(&flags.proxyCertPath, "proxy-cert", "", "path to a certificate the proxy will trust")
+	cmd.Flags().StringVar(&flags.collectorConfigPath, "collector-config", "", "path to an OpenTelemetry collector config file")
+	addPullFlag(cmd, &flags.pullPolicy)
+	addNotifyFlags(cmd, &flags.SharedFlags)
+	cmd.Flags().StringArrayVarP(&flags.volumes, "volume", "v", nil, "mount volumes in Docker")
+	cmd.Flags().StringArrayVar(&flags.extraHosts, "extra-hosts", nil, "Docker extra hosts setting on the proxy")
+	cmd.Flags().DurationVarP(&flags.timeout, "timeout", "t", 0, "max time to run each update")
+
+	return cmd
+}
+
+func readScheduleFile(file string) ([]queue.ScheduledJob, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open schedule file: %w", err)
+	}
+	var config scheduleConfig
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode schedule file: %w", err)
+	}
+	if len(config.Jobs) == 0 {
+		return nil, fmt.Errorf("the schedule file %s has no jobs", file)
+	}
+	if config.Keep == 0 {
+		config.Keep = defaultKeep
+	}
+
+	var jobs []queue.ScheduledJob
+	names := map[string]bool{}
+	for i, entry := range config.Jobs {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("job %d in the schedule file has no name", i+1)
+		}
+		if names[entry.Name] {
+			return nil, fmt.Errorf("the schedule file has more than one job named %s", entry.Name)
+		}
+		names[entry.Name] = true
+
+		schedule, err := queue.ParseSchedule(entry.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", entry.Name, err)
+		}
+
+		var input *model.Input
+		switch {
+		case entry.File != "" && entry.Job != nil:
+			return nil, fmt.Errorf("job %s has both a file and a job, only one can be used", entry.Name)
+		case entry.File != "":
+			filename := entry.File
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(filepath.Dir(file), filename)
+			}
+			if input, err = readInputFile(filename); err != nil {
+				return nil, fmt.Errorf("job %s: %w", entry.Name, err)
+			}
+		case entry.Job != nil:
+			input = &model.Input{Job: *entry.Job, Credentials: entry.Credentials}
+		default:
+			return nil, fmt.Errorf("job %s needs a file or a job", entry.Name)
+		}
+
+		keep := entry.Keep
+		if keep == 0 {
+			keep = config.Keep
+		}
+		if keep < 1 {
+			return nil, fmt.Errorf("job %s must keep at least 1 run", entry.Name)
+		}
+
+		jobs = append(jobs, queue.ScheduledJob{
+			Name:     entry.Name,
+			Spec:     entry.Schedule,
+			Schedule: schedule,
+			Input:    input,
+			Keep:     keep,
+		})
+	}
+	return jobs, nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_readScheduleFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	write("quote.yml", "job:\n  package-manager: go_modules\n  source:\n    repo: rsc/quote\n")
+
+	t.Run("reads jobs from files and inline", func(t *testing.T) {
+		jobs, err := readScheduleFile(write("schedule.yml", `
+keep: 3
+jobs:
+  - name: quote
+    schedule: "@daily"
+    file: quote.yml
+  - name: cli
+    schedule: "30 2 * * 1-5"
+    keep: 7
+    job:
+      package-manager: go_modules
+      source:
+        repo: dependabot/cli
+    credentials:
+      - type: git_source
+        host: github.com
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) != 2 {
+			t.Fatalf("expected 2 jobs, got %d", len(jobs))
+		}
+		if jobs[0].Input.Job.Source.Repo != "rsc/quote" || jobs[0].Keep != 3 {
+			t.Errorf("unexpected job %+v", jobs[0])
+		}
+		if jobs[1].Input.Job.Source.Repo != "dependabot/cli" || jobs[1].Keep != 7 || len(jobs[1].Input.Credentials) != 1 {
+			t.Errorf("unexpected job %+v", jobs[1])
+		}
+	})
+
+	for name, content := range map[string]string{
+		"no jobs":          "jobs: []\n",
+		"no name":          "jobs:\n  - schedule: '@daily'\n    file: quote.yml\n",
+		"duplicate name":   "jobs:\n  - {name: a, schedule: '@daily', file: quote.yml}\n  - {name: a, schedule: '@daily', file: quote.yml}\n",
+		"invalid schedule": "jobs:\n  - {name: a, schedule: 'sometimes', file: quote.yml}\n",
+		"no input":         "jobs:\n  - {name: a, schedule: '@daily'}\n",
+		"missing file":     "jobs:\n  - {name: a, schedule: '@daily', file: missing.yml}\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := readScheduleFile(write("invalid.yml", content)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dependabot/cli/internal/infra"
+	"github.com/dependabot/cli/internal/model"
+	"github.com/dependabot/cli/internal/queue"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = NewServeCommand()
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+}
+
+type ServeFlags struct {
+	SharedFlags
+	listen        string
+	dir           string
+	concurrency   int
+	credentialEnv []string
+}
+
+func NewServeCommand() *cobra.Command {
+	var flags ServeFlags
+
+	cmd := &cobra.Command{
+		Use:   "serve [flags]",
+		Short: "Run update jobs submitted over HTTP",
+		Long: heredoc.Doc(`
+			Run a queue of update jobs. Jobs are submitted and inspected with an HTTP API:
+
+			  POST /jobs              submit a job, the body is an input file in JSON or YAML
+			  GET  /jobs              list the jobs
+			  GET  /jobs/{id}         the status of a job
+			  GET  /jobs/{id}/logs    the output of the containers
+			  GET  /jobs/{id}/output  the scenario recorded by a finished job
+
+			Requests need the bearer token from $DEPENDABOT_SERVE_TOKEN, or the one printed at
+			startup when it isn't set, and jobs must be sent as application/json or application/yaml.
+
+			Submitted credentials can only reference the environment variables given with
+			--credential-env, like $LOCAL_GITHUB_ACCESS_TOKEN, since they're sent to the hosts the
+			job names.
+
+			The input, logs, and output of every job are kept in the jobs directory.
+		`),
+		Example: heredoc.Doc(`
+		    $ dependabot serve --concurrency 2 --credential-env LOCAL_GITHUB_ACCESS_TOKEN
+		    $ curl -H "Authorization: Bearer $DEPENDABOT_SERVE_TOKEN" -H "Content-Type: application/yaml" \
+		        --data-binary @input.yml localhost:8080/jobs
+	    `),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token := os.Getenv("DEPENDABOT_SERVE_TOKEN")
+			if token == "" {
+				b := make([]byte, 16)
+				if _, err := rand.Read(b); err != nil {
+					return fmt.Errorf("failed to generate a token: %w", err)
+				}
+				token = hex.EncodeToString(b)
+				log.Printf("Requests need the token %s, set $DEPENDABOT_SERVE_TOKEN to choose it\n", token)
+			}
+			// nil would let the jobs reference any variable
+			credentialEnv := append([]string{}, flags.credentialEnv...)
+
+			q, err := queue.New(flags.dir, flags.concurrency, func(input *model.Input, logs io.Writer, output string) error {
+				return runQueuedJob(input, logs, output, &flags.SharedFlags, credentialEnv)
+			})
+			if err != nil {
+				return err
+			}
+
+			l, err := net.Listen("tcp", flags.listen)
+			if err != nil {
+				q.Close()
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+			srv := &http.Server{Handler: queue.Authorize(token, q), ReadHeaderTimeout: 5 * time.Second}
+
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+			go func() {
+				<-signals
+				log.Println("Shutting down, waiting for running jobs to stop")
+				_ = srv.Shutdown(context.Background())
+			}()
+
+			log.Printf("Serving jobs from %s on http://%s\n", flags.dir, l.Addr())
+			err = srv.Serve(l)
+			q.Close()
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.listen, "listen", "127.0.0.1:8080", "address to serve the API on")
+	cmd.Flags().StringVar(&flags.dir, "dir", "dependabot-jobs", "directory to keep the jobs in")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", 1, "maximum number of jobs to run at once")
+	cmd.Flags().StringArrayVar(&flags.credentialEnv, "credential-env", nil, "environment variable the submitted credentials may reference")
+
+	cmd.Flags().StringVar(&flags.proxyCertPath, "proxy-cert", "", "path to a certificate the proxy will trust")
+	cmd.Flags().StringVar(&flags.collectorConfigPath, "collector-config", "", "path to an OpenTelemetry collector config file")
+	addPullFlag(cmd, &flags.pullPolicy)
+	addNotifyFlags(cmd, &flags.SharedFlags)
+	cmd.Flags().StringArrayVarP(&flags.volumes, "volume", "v", nil, "mount volumes in Docker")
+	cmd.Flags().StringArrayVar(&flags.extraHosts, "extra-hosts", nil, "Docker extra hosts setting on the proxy")
+	cmd.Flags().DurationVarP(&flags.timeout, "timeout", "t", 0, "max time to run each update")
+
+	return cmd
+}
+
+// runQueuedJob runs a job from the queue like the update command would. The credentials can only
+// reference the variables in credentialEnv, unless it's nil.
+func runQueuedJob(input *model.Input, logs io.Writer, output string, flags *SharedFlags, credentialEnv []string) error {
+	processInput(input, &UpdateFlags{SharedFlags: *flags})
+
+	return infra.Run(infra.RunParams{
+		CollectorConfigPath: flags.collectorConfigPath,
+		CollectorImage:      collectorImage,
+		Creds:               input.Credentials,
+		CredentialEnv:       credentialEnv,
+		ExtraHosts:          flags.extraHosts,
+		Job:                 &input.Job,
+		LogWriter:           logs,
+		NotifySecret:        notifySecret(flags.notifySecret),
+		NotifyURLs:          flags.notifyURLs,
+		Output:              output,
+		ProxyCertPath:       flags.proxyCertPath,
+		ProxyImage:          proxyImage,
+		PullPolicy:          flags.pullPolicy,
+		Timeout:             flags.timeout,
+		UpdaterImage:        updaterImage,
+		Volumes:             flags.volumes,
+	})
+}
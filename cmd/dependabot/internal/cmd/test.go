@@ -34,6 +34,17 @@ func NewTestCommand() *cobra.Command {
 
 			processInput(&scenario.Input, nil)
 
+			updaterImage, proxyImage, collectorImage := updaterImage, proxyImage, collectorImage
+			if flags.pinImages {
+				if images := scenario.Input.Images; images != nil {
+					updaterImage = firstNonEmpty(images.Updater, updaterImage)
+					proxyImage = firstNonEmpty(images.Proxy, proxyImage)
+					collectorImage = firstNonEmpty(images.Collector, collectorImage)
+				} else {
+					log.Println("Warning: the scenario doesn't record any images to pin")
+				}
+			}
+
 			if err := executeTestJob(infra.RunParams{
 				CacheDir:            flags.cache,
 				CollectorConfigPath: flags.collectorConfigPath,
@@ -41,15 +52,22 @@ func NewTestCommand() *cobra.Command {
 				Creds:               scenario.Input.Credentials,
 				Debug:               flags.debugging,
 				Expected:            scenario.Output,
+				ExpectedImages:      scenario.Input.Images,
 				ExtraHosts:          flags.extraHosts,
+				GitRepo:             flags.gitRepo,
 				InputName:           flags.file,
 				InputRaw:            inputRaw,
 				Job:                 &scenario.Input.Job,
 				LocalDir:            flags.local,
+				LocalExclude:        flags.localExclude,
+				NotifySecret:        notifySecret(flags.notifySecret),
+				NotifyURLs:          flags.notifyURLs,
 				Output:              flags.output,
+				PreserveGit:         flags.preserveGit,
 				ProxyCertPath:       flags.proxyCertPath,
 				ProxyImage:          proxyImage,
-				PullImages:          flags.pullImages,
+				PullPolicy:          flags.pullPolicy,
+				StrictSpec:          true,
 				Timeout:             flags.timeout,
 				UpdaterImage:        updaterImage,
 				Volumes:             flags.volumes,
@@ -66,10 +84,16 @@ func NewTestCommand() *cobra.Command {
 	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "write scenario to file")
 	cmd.Flags().StringVar(&flags.cache, "cache", "", "cache import/export directory")
 	cmd.Flags().StringVar(&flags.local, "local", "", "local directory to use as fetched source")
+	cmd.Flags().StringArrayVar(&flags.localExclude, "local-exclude", nil, "pattern of files in the --local directory not to copy, like a .gitignore line")
+	cmd.Flags().BoolVar(&flags.preserveGit, "preserve-git", false, "copy the git history of the --local directory instead of creating a new repository")
+	cmd.Flags().StringVar(&flags.gitRepo, "git-repo", "", "bare git repository to serve to the updater over HTTPS as the job's repository")
+	cmd.MarkFlagsMutuallyExclusive("local", "git-repo")
 	cmd.Flags().StringVar(&flags.proxyCertPath, "proxy-cert", "", "path to a certificate the proxy will trust")
 	cmd.Flags().StringVar(&flags.collectorConfigPath, "collector-config", "", "path to an OpenTelemetry collector config file")
-	cmd.Flags().BoolVar(&flags.pullImages, "pull", true, "pull the image if it isn't present")
+	addPullFlag(cmd, &flags.pullPolicy)
+	addNotifyFlags(cmd, &flags)
 	cmd.Flags().BoolVar(&flags.debugging, "debug", false, "run an interactive shell inside the updater")
+	cmd.Flags().BoolVar(&flags.pinImages, "pin-images", false, "run with the image digests recorded in the scenario")
 	cmd.Flags().StringArrayVarP(&flags.volumes, "volume", "v", nil, "mount volumes in Docker")
 	cmd.Flags().StringArrayVar(&flags.extraHosts, "extra-hosts", nil, "Docker extra hosts setting on the proxy")
 	cmd.Flags().DurationVarP(&flags.timeout, "timeout", "t", 0, "max time to run an update")
@@ -95,6 +119,15 @@ func readScenarioFile(file string) (*model.Scenario, []byte, error) {
 	return &scenario, data, nil
 }
 
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 func init() {
 	rootCmd.AddCommand(testCmd)
 }
@@ -1,8 +1,11 @@
 package cmd
 
 import (
-	"github.com/dependabot/cli/internal/infra"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/dependabot/cli/internal/infra"
 )
 
 func TestTestCommand(t *testing.T) {
@@ -35,4 +38,34 @@ func TestTestCommand(t *testing.T) {
 			t.Errorf("expected package manager to be set")
 		}
 	})
+	t.Run("Pin the recorded images", func(t *testing.T) {
+		var actualParams *infra.RunParams
+		executeTestJob = func(params infra.RunParams) error {
+			actualParams = &params
+			return nil
+		}
+		const updater = "ghcr.io/dependabot/dependabot-updater-gomod@sha256:4b1d1e5d1a7c0c3c5dcd8bd0b8f4b1f2a0e3c2d5b1a7c0c3c5dcd8bd0b8f4b1f"
+		scenario := filepath.Join(t.TempDir(), "scenario.yml")
+		data := "input:\n  job:\n    package-manager: go_modules\n  images:\n    updater: " + updater + "\n"
+		if err := os.WriteFile(scenario, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := NewTestCommand()
+		if err := cmd.ParseFlags([]string{"-f", scenario, "--pin-images"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := cmd.RunE(cmd, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actualParams.UpdaterImage != updater {
+			t.Errorf("expected the updater image to be pinned, got %v", actualParams.UpdaterImage)
+		}
+		if actualParams.ProxyImage != proxyImage {
+			t.Errorf("expected the proxy image to be unchanged, got %v", actualParams.ProxyImage)
+		}
+		if actualParams.ExpectedImages == nil || actualParams.ExpectedImages.Updater != updater {
+			t.Errorf("expected the recorded images to be passed along")
+		}
+	})
 }
@@ -11,6 +11,7 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"strconv"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/dependabot/cli/internal/infra"
@@ -33,9 +34,17 @@ type UpdateFlags struct {
 	branch          string
 	local           string
 	commit          string
+	hostname        string
+	apiEndpoint     string
 	dependencies    []string
 	inputServerPort int
 	apiUrl          string
+	forwardURL      string
+	strictSpec      bool
+	apply           string
+	patches         string
+	branches        bool
+	state           string
 }
 
 func NewUpdateCommand() *cobra.Command {
@@ -46,6 +55,7 @@ func NewUpdateCommand() *cobra.Command {
 		Short: "Perform an update job",
 		Example: heredoc.Doc(`
 		    $ dependabot update go_modules rsc/quote
+		    $ dependabot update go_modules https://github.com/rsc/quote/tree/master/buggy
 		    $ dependabot update -f input.yml
 	    `),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -66,6 +76,11 @@ func NewUpdateCommand() *cobra.Command {
 
 			processInput(input, &flags)
 
+			apply, applyIndex, err := parseApply(flags.apply)
+			if err != nil {
+				return err
+			}
+
 			var writer io.Writer
 			if !flags.debugging {
 				writer = os.Stdout
@@ -85,12 +100,24 @@ func NewUpdateCommand() *cobra.Command {
 				Output:              flags.output,
 				ProxyCertPath:       flags.proxyCertPath,
 				ProxyImage:          proxyImage,
-				PullImages:          flags.pullImages,
+				PullPolicy:          flags.pullPolicy,
 				Timeout:             flags.timeout,
 				UpdaterImage:        updaterImage,
 				Volumes:             flags.volumes,
 				Writer:              writer,
 				ApiUrl:              flags.apiUrl,
+				ForwardURL:          flags.forwardURL,
+				StrictSpec:          flags.strictSpec,
+				Apply:               apply,
+				ApplyIndex:          applyIndex,
+				PatchesDir:          flags.patches,
+				Branches:            flags.branches,
+				PreserveGit:         flags.preserveGit,
+				GitRepo:             flags.gitRepo,
+				LocalExclude:        flags.localExclude,
+				StateFile:           flags.state,
+				NotifyURLs:          flags.notifyURLs,
+				NotifySecret:        notifySecret(flags.notifySecret),
 			}); err != nil {
 				if errors.Is(err, context.DeadlineExceeded) {
 					log.Fatalf("update timed out after %s", flags.timeout)
@@ -108,24 +135,54 @@ func NewUpdateCommand() *cobra.Command {
 	cmd.Flags().StringVarP(&flags.branch, "branch", "b", "", "target branch to update")
 	cmd.Flags().StringVarP(&flags.directory, "directory", "d", "/", "directory to update")
 	cmd.Flags().StringVarP(&flags.commit, "commit", "", "", "commit to update")
+	cmd.Flags().StringVar(&flags.hostname, "hostname", "", "host of the repository, like a GitHub Enterprise Server")
+	cmd.Flags().StringVar(&flags.apiEndpoint, "api-endpoint", "", "API of the --hostname, defaults to https://<hostname>/api/v3/ for GitHub Enterprise Server")
 	cmd.Flags().StringArrayVarP(&flags.dependencies, "dep", "", nil, "dependencies to update")
 
 	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "write scenario to file")
 	cmd.Flags().StringVar(&flags.cache, "cache", "", "cache import/export directory")
 	cmd.Flags().StringVar(&flags.local, "local", "", "local directory to use as fetched source")
+	cmd.Flags().StringArrayVar(&flags.localExclude, "local-exclude", nil, "pattern of files in the --local directory not to copy, like a .gitignore line")
+	cmd.Flags().BoolVar(&flags.preserveGit, "preserve-git", false, "copy the git history of the --local directory instead of creating a new repository")
+	cmd.Flags().StringVar(&flags.gitRepo, "git-repo", "", "bare git repository to serve to the updater over HTTPS as the job's repository")
+	cmd.MarkFlagsMutuallyExclusive("local", "git-repo")
+	cmd.Flags().StringVar(&flags.apply, "apply", "", "write the changes of all proposed PRs, or only the nth PR, back into the --local directory")
+	cmd.Flags().Lookup("apply").NoOptDefVal = "all"
+	cmd.Flags().StringVar(&flags.patches, "patches", "", "directory to write a patch for each proposed PR to")
+	cmd.Flags().BoolVar(&flags.branches, "branches", false, "create a branch in the --local git repository for each proposed PR")
+	cmd.Flags().StringVar(&flags.state, "state", "", "file recording the PRs of each run, so that later runs see them as existing PRs")
 	cmd.Flags().StringVar(&flags.proxyCertPath, "proxy-cert", "", "path to a certificate the proxy will trust")
 	cmd.Flags().StringVar(&flags.collectorConfigPath, "collector-config", "", "path to an OpenTelemetry collector config file")
-	cmd.Flags().BoolVar(&flags.pullImages, "pull", true, "pull the image if it isn't present")
+	addPullFlag(cmd, &flags.pullPolicy)
+	addNotifyFlags(cmd, &flags.SharedFlags)
 	cmd.Flags().BoolVar(&flags.debugging, "debug", false, "run an interactive shell inside the updater")
 	cmd.Flags().StringArrayVarP(&flags.volumes, "volume", "v", nil, "mount volumes in Docker")
 	cmd.Flags().StringArrayVar(&flags.extraHosts, "extra-hosts", nil, "Docker extra hosts setting on the proxy")
 	cmd.Flags().DurationVarP(&flags.timeout, "timeout", "t", 0, "max time to run an update")
 	cmd.Flags().IntVar(&flags.inputServerPort, "input-port", 0, "port to use for securely passing input to the updater")
 	cmd.Flags().StringVarP(&flags.apiUrl, "api-url", "a", "", "the api dependabot should connect to.")
+	cmd.Flags().StringVar(&flags.forwardURL, "forward-url", "", "job API to forward the calls to while recording them, authenticated with $DEPENDABOT_JOB_TOKEN")
+	cmd.MarkFlagsMutuallyExclusive("api-url", "forward-url")
+	cmd.Flags().BoolVar(&flags.strictSpec, "strict-spec", false, "fail when a call doesn't match the API spec, instead of logging a warning")
 
 	return cmd
 }
 
+// parseApply parses the --apply flag, which is empty when not applying, "all", or the 1-based index of a PR.
+func parseApply(value string) (apply bool, index int, err error) {
+	switch value {
+	case "":
+		return false, 0, nil
+	case "all":
+		return true, 0, nil
+	}
+	index, err = strconv.Atoi(value)
+	if err != nil || index < 1 {
+		return false, 0, fmt.Errorf("--apply must be a PR number starting at 1, got %q", value)
+	}
+	return true, index, nil
+}
+
 func extractInput(cmd *cobra.Command, flags *UpdateFlags) (*model.Input, error) {
 	hasFile := flags.file != ""
 	hasArguments := len(cmd.Flags().Args()) > 0
@@ -204,10 +261,50 @@ func readArguments(cmd *cobra.Command, flags *UpdateFlags) (*model.Input, error)
 		}
 	}
 
-	if flags.branch != "" && flags.commit != "" {
+	provider, directory, branch := flags.provider, flags.directory, flags.branch
+	host, endpoint := flags.hostname, flags.apiEndpoint
+	if isRepoURL(repo) {
+		// the flags given explicitly win over what the URL says, and --provider over the provider
+		// guessed for a self-hosted server
+		urlProvider := ""
+		if cmd.Flags().Changed("provider") {
+			urlProvider = provider
+		}
+		parsed, err := parseRepoURL(repo, urlProvider)
+		if err != nil {
+			return nil, err
+		}
+		provider, repo = parsed.Provider, parsed.Repo
+		if parsed.Directory != "" && !cmd.Flags().Changed("directory") {
+			directory = parsed.Directory
+		}
+		if parsed.Branch != "" && branch == "" && flags.commit == "" {
+			branch = parsed.Branch
+		}
+		if parsed.Hostname != "" && flags.hostname == "" {
+			host, endpoint = parsed.Hostname, firstNonEmpty(flags.apiEndpoint, parsed.APIEndpoint)
+		}
+	}
+
+	if branch != "" && flags.commit != "" {
 		return nil, errors.New("cannot specify both branch and commit")
 	}
 
+	// checked once the URL's hostname is in, which an --api-endpoint can go with
+	var hostname, apiEndpoint *string
+	if host != "" {
+		if endpoint == "" {
+			if provider != "github" {
+				return nil, errors.New("--hostname requires --api-endpoint for providers other than github")
+			}
+			// where GitHub Enterprise Server has its REST API
+			endpoint = fmt.Sprintf("https://%s/api/v3/", host)
+		}
+		hostname, apiEndpoint = &host, &endpoint
+	} else if endpoint != "" {
+		return nil, errors.New("--api-endpoint requires --hostname")
+	}
+
 	input := &model.Input{
 		Job: model.Job{
 			PackageManager:             packageManager,
@@ -221,13 +318,13 @@ func readArguments(cmd *cobra.Command, flags *UpdateFlags) (*model.Input, error)
 			SecurityAdvisories:         []model.Advisory{},
 			SecurityUpdatesOnly:        false,
 			Source: model.Source{
-				Provider:    flags.provider,
+				Provider:    provider,
 				Repo:        repo,
-				Directory:   flags.directory,
+				Directory:   directory,
 				Commit:      flags.commit,
-				Branch:      flags.branch,
-				Hostname:    nil,
-				APIEndpoint: nil,
+				Branch:      branch,
+				Hostname:    hostname,
+				APIEndpoint: apiEndpoint,
 			},
 			UpdateSubdependencies: false,
 			UpdatingAPullRequest:  false,
@@ -278,6 +375,15 @@ func processInput(input *model.Input, flags *UpdateFlags) {
 	}
 
 	azureRepo := model.NewAzureRepo(input.Job.PackageManager, input.Job.Source.Repo, input.Job.Source.Directory)
+	if azureRepo != nil && (input.Job.Source.Provider == "azure" || azureRepo.Path() != input.Job.Source.Repo) {
+		// the repo may be a URL, the updater needs the path and the host it's on
+		input.Job.Source.Provider = "azure"
+		input.Job.Source.Repo = azureRepo.Path()
+		if azureRepo.IsServer() && input.Job.Source.Hostname == nil {
+			input.Job.Source.Hostname = &azureRepo.Hostname
+			input.Job.Source.APIEndpoint = &azureRepo.APIEndpoint
+		}
+	}
 
 	// As a convenience, fill in a git_source if credentials are in the environment and a git_source
 	// doesn't already exist. This way the user doesn't run out of calls from being anonymous.
@@ -291,7 +397,7 @@ func processInput(input *model.Input, flags *UpdateFlags) {
 			break
 		}
 	}
-	if hasLocalAzureToken && flags.apiUrl != "" && azureRepo != nil {
+	if hasLocalAzureToken && flags != nil && flags.apiUrl != "" && azureRepo != nil {
 		u, _ := url.Parse(flags.apiUrl)
 		input.Credentials = append(input.Credentials, model.Credential{
 			"type":     "git_source",
@@ -303,9 +409,10 @@ func processInput(input *model.Input, flags *UpdateFlags) {
 
 	if hasLocalToken && !isGitSourceInCreds {
 		log.Println("Inserting $LOCAL_GITHUB_ACCESS_TOKEN into credentials")
+		host := githubHost(&input.Job.Source)
 		input.Credentials = append(input.Credentials, model.Credential{
 			"type":     "git_source",
-			"host":     "github.com",
+			"host":     host,
 			"username": "x-access-token",
 			"password": "$LOCAL_GITHUB_ACCESS_TOKEN",
 		})
@@ -313,7 +420,7 @@ func processInput(input *model.Input, flags *UpdateFlags) {
 			// Add the metadata since the next section will be skipped.
 			input.Job.CredentialsMetadata = append(input.Job.CredentialsMetadata, map[string]any{
 				"type": "git_source",
-				"host": "github.com",
+				"host": host,
 			})
 		}
 	}
@@ -321,61 +428,34 @@ func processInput(input *model.Input, flags *UpdateFlags) {
 	if hasLocalAzureToken && !isGitSourceInCreds && azureRepo != nil {
 		log.Println("Inserting $LOCAL_AZURE_ACCESS_TOKEN into credentials")
 		log.Printf("Inserting artifacts credentials for %s organization.", azureRepo.Org)
-		input.Credentials = append(input.Credentials, model.Credential{
-			"type":     "git_source",
-			"host":     "dev.azure.com",
-			"username": "x-access-token",
-			"password": "$LOCAL_AZURE_ACCESS_TOKEN",
-		})
-		if len(input.Job.CredentialsMetadata) > 0 {
-			// Add the metadata since the next section will be skipped.
-			input.Job.CredentialsMetadata = append(input.Job.CredentialsMetadata, map[string]any{
-				"type": "git_source",
-				"host": "dev.azure.com",
-			})
-		}
-		input.Credentials = append(input.Credentials, model.Credential{
-			"type":     "git_source",
-			"host":     fmt.Sprintf("%s.pkgs.visualstudio.com", azureRepo.Org),
-			"username": "x-access-token",
-			"password": "$LOCAL_AZURE_ACCESS_TOKEN",
-		})
-		if len(input.Job.CredentialsMetadata) > 0 {
-			// Add the metadata since the next section will be skipped.
-			input.Job.CredentialsMetadata = append(input.Job.CredentialsMetadata, map[string]any{
-				"type": "git_source",
-				"host": fmt.Sprintf("%s.pkgs.visualstudio.com", azureRepo.Org),
-			})
-		}
-		input.Credentials = append(input.Credentials, model.Credential{
-			"type":     "git_source",
-			"host":     "pkgs.dev.azure.com",
-			"username": "x-access-token",
-			"password": "$LOCAL_AZURE_ACCESS_TOKEN",
-		})
-		if len(input.Job.CredentialsMetadata) > 0 {
-			// Add the metadata since the next section will be skipped.
-			input.Job.CredentialsMetadata = append(input.Job.CredentialsMetadata, map[string]any{
-				"type": "git_source",
-				"host": "pkgs.dev.azure.com",
+		for _, host := range azureRepo.CredentialHosts() {
+			input.Credentials = append(input.Credentials, model.Credential{
+				"type":     "git_source",
+				"host":     host,
+				"username": "x-access-token",
+				"password": "$LOCAL_AZURE_ACCESS_TOKEN",
 			})
+			if len(input.Job.CredentialsMetadata) > 0 {
+				// Add the metadata since the next section will be skipped.
+				input.Job.CredentialsMetadata = append(input.Job.CredentialsMetadata, map[string]any{
+					"type": "git_source",
+					"host": host,
+				})
+			}
 		}
 	}
 
+	if !isGitSourceInCreds {
+		addProviderCredentials(input)
+	}
+
 	// As a convenience, fill credentials-metadata if credentials are provided
 	// which is what happens in production. This way the user doesn't have to
 	// specify credentials-metadata in the scenario file unless they want to.
 	if len(input.Job.CredentialsMetadata) == 0 {
 		log.Println("Adding missing credentials-metadata into job definition")
 		for _, credential := range input.Credentials {
-			entry := make(map[string]any)
-			for k, v := range credential {
-				// Updater does not get credentials.
-				if k != "token" && k != "password" && k != "key" && k != "auth-key" {
-					entry[k] = v
-				}
-			}
-			input.Job.CredentialsMetadata = append(input.Job.CredentialsMetadata, entry)
+			input.Job.CredentialsMetadata = append(input.Job.CredentialsMetadata, credentialMetadata(credential))
 		}
 	}
 }
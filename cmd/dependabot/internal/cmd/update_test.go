@@ -59,6 +59,20 @@ func Test_processInput(t *testing.T) {
 		}
 	})
 
+	t.Run("adds the local token for GitHub Enterprise Server", func(t *testing.T) {
+		t.Setenv("LOCAL_GITHUB_ACCESS_TOKEN", "token")
+		hostname := "ghes.example.com"
+		apiEndpoint := "https://ghes.example.com/api/v3/"
+
+		var input model.Input
+		input.Job.Source = model.Source{Provider: "github", Repo: "org/repo", Hostname: &hostname, APIEndpoint: &apiEndpoint}
+		processInput(&input, nil)
+
+		if len(input.Credentials) != 1 || input.Credentials[0]["host"] != "ghes.example.com" {
+			t.Errorf("unexpected credentials %v", input.Credentials)
+		}
+	})
+
 	t.Run("adds metadata when credentials are provided", func(t *testing.T) {
 		var input model.Input
 		input.Credentials = []model.Credential{
@@ -94,6 +108,41 @@ func Test_processInput(t *testing.T) {
 			t.Error("expected credentials metadata to be added")
 		}
 	})
+
+	t.Run("normalizes Azure DevOps URLs and adds their credentials", func(t *testing.T) {
+		t.Setenv("LOCAL_GITHUB_ACCESS_TOKEN", "")
+		t.Setenv("LOCAL_AZURE_ACCESS_TOKEN", "token")
+
+		var input model.Input
+		input.Job.Source = model.Source{Provider: "github", Repo: "https://tfs.example.com/tfs/DefaultCollection/Project/_git/Repo"}
+		processInput(&input, nil)
+
+		source := input.Job.Source
+		if source.Provider != "azure" || source.Repo != "DefaultCollection/Project/_git/Repo" {
+			t.Errorf("unexpected source %v %v", source.Provider, source.Repo)
+		}
+		if source.Hostname == nil || *source.Hostname != "tfs.example.com" || *source.APIEndpoint != "https://tfs.example.com/tfs/" {
+			t.Errorf("unexpected hostname and API endpoint %v %v", source.Hostname, source.APIEndpoint)
+		}
+		if len(input.Credentials) != 1 || input.Credentials[0]["host"] != "tfs.example.com" {
+			t.Errorf("unexpected credentials %v", input.Credentials)
+		}
+
+		input = model.Input{}
+		input.Job.Source = model.Source{Provider: "azure", Repo: "https://my-org.visualstudio.com/Project/_git/Repo"}
+		processInput(&input, nil)
+
+		if input.Job.Source.Repo != "my-org/Project/_git/Repo" || input.Job.Source.Hostname != nil {
+			t.Errorf("unexpected source %+v", input.Job.Source)
+		}
+		var hosts []string
+		for _, cred := range input.Credentials {
+			hosts = append(hosts, cred["host"].(string))
+		}
+		if strings.Join(hosts, ",") != "dev.azure.com,my-org.pkgs.visualstudio.com,pkgs.dev.azure.com" {
+			t.Errorf("unexpected credential hosts %v", hosts)
+		}
+	})
 }
 
 func Test_extractInput(t *testing.T) {
@@ -110,6 +159,83 @@ func Test_extractInput(t *testing.T) {
 			t.Errorf("expected package manager to be go_modules, got %s", input.Job.PackageManager)
 		}
 	})
+	t.Run("test GitHub Enterprise Server arguments", func(t *testing.T) {
+		cmd := NewUpdateCommand()
+		if err := cmd.ParseFlags([]string{"go_modules", "org/repo"}); err != nil {
+			t.Fatal(err)
+		}
+		input, err := extractInput(cmd, &UpdateFlags{provider: "github", hostname: "ghes.example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		source := input.Job.Source
+		if *source.Hostname != "ghes.example.com" || *source.APIEndpoint != "https://ghes.example.com/api/v3/" {
+			t.Errorf("unexpected hostname and API endpoint %v %v", *source.Hostname, *source.APIEndpoint)
+		}
+
+		if _, err = extractInput(cmd, &UpdateFlags{provider: "github", apiEndpoint: "https://ghes.example.com/api/v3/"}); err == nil {
+			t.Error("expected an error for an API endpoint without a hostname")
+		}
+		if _, err = extractInput(cmd, &UpdateFlags{provider: "gitlab", hostname: "gitlab.example.com"}); err == nil {
+			t.Error("expected an error for a GitLab hostname without an API endpoint")
+		}
+	})
+	t.Run("test repo URL", func(t *testing.T) {
+		cmd := NewUpdateCommand()
+		if err := cmd.ParseFlags([]string{"go_modules", "https://gitlab.example.com/group/repo/-/tree/main/api"}); err != nil {
+			t.Fatal(err)
+		}
+		input, err := extractInput(cmd, &UpdateFlags{provider: "github", directory: "/"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		source := input.Job.Source
+		if source.Provider != "gitlab" || source.Repo != "group/repo" || source.Branch != "main" || source.Directory != "/api" {
+			t.Errorf("unexpected source %+v", source)
+		}
+		if *source.Hostname != "gitlab.example.com" || *source.APIEndpoint != "https://gitlab.example.com/api/v4" {
+			t.Errorf("unexpected hostname and API endpoint %v %v", *source.Hostname, *source.APIEndpoint)
+		}
+
+		// the commit wins over the URL's branch
+		input, err = extractInput(cmd, &UpdateFlags{provider: "github", commit: "abc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if input.Job.Source.Branch != "" || input.Job.Source.Commit != "abc" {
+			t.Errorf("unexpected source %+v", input.Job.Source)
+		}
+
+		// the URL has the hostname an --api-endpoint needs
+		input, err = extractInput(cmd, &UpdateFlags{provider: "github", apiEndpoint: "https://gitlab.example.com/custom/api/v4"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		source = input.Job.Source
+		if *source.Hostname != "gitlab.example.com" || *source.APIEndpoint != "https://gitlab.example.com/custom/api/v4" {
+			t.Errorf("unexpected hostname and API endpoint %v %v", *source.Hostname, *source.APIEndpoint)
+		}
+
+		cmd = NewUpdateCommand()
+		if err = cmd.ParseFlags([]string{"go_modules", "https://github.com/org/repo", "--provider", "gitlab"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = extractInput(cmd, &UpdateFlags{provider: "gitlab"}); err == nil {
+			t.Error("expected an error for a provider that doesn't match the URL")
+		}
+
+		// the provider of a self-hosted server can be given when it's guessed wrong
+		cmd = NewUpdateCommand()
+		if err = cmd.ParseFlags([]string{"go_modules", "https://git.example.com/group/repo", "--provider", "gitlab"}); err != nil {
+			t.Fatal(err)
+		}
+		if input, err = extractInput(cmd, &UpdateFlags{provider: "gitlab"}); err != nil {
+			t.Fatal(err)
+		}
+		if source = input.Job.Source; source.Provider != "gitlab" || *source.APIEndpoint != "https://git.example.com/api/v4" {
+			t.Errorf("unexpected source %+v", source)
+		}
+	})
 	t.Run("test file", func(t *testing.T) {
 		cmd := NewUpdateCommand()
 		input, err := extractInput(cmd, &UpdateFlags{
@@ -191,3 +317,17 @@ func Test_extractInput(t *testing.T) {
 		}
 	})
 }
+
+func Test_notifySecret(t *testing.T) {
+	t.Setenv("DEPENDABOT_NOTIFY_SECRET", "hunter2")
+
+	if usage := NewUpdateCommand().UsageString(); strings.Contains(usage, "hunter2") {
+		t.Errorf("expected the usage not to show the secret:\n%s", usage)
+	}
+	if secret := notifySecret(""); secret != "hunter2" {
+		t.Errorf("expected the secret from the environment, got %q", secret)
+	}
+	if secret := notifySecret("flag"); secret != "flag" {
+		t.Errorf("expected the flag to win, got %q", secret)
+	}
+}
@@ -0,0 +1,247 @@
+package infra
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+// createdPullRequests returns the create_pull_request outputs in the order they were received.
+func createdPullRequests(outputs []model.Output) ([]model.CreatePullRequest, error) {
+	var prs []model.CreatePullRequest
+	for _, out := range outputs {
+		if out.Type != "create_pull_request" {
+			continue
+		}
+		pr, ok := out.Expect.Data.(model.CreatePullRequest)
+		if !ok {
+			return nil, fmt.Errorf("failed to decode CreatePullRequest object")
+		}
+		prs = append(prs, pr)
+	}
+	return prs, nil
+}
+
+// applyPullRequests writes the files changed by the proposed pull requests into dir. An index of zero
+// applies every pull request in order, otherwise only the index-th (starting at 1) is applied.
+func applyPullRequests(ctx context.Context, dir string, index int, outputs []model.Output, original func(model.DependencyFile) model.DependencyFile) error {
+	prs, err := createdPullRequests(outputs)
+	if err != nil {
+		return err
+	}
+	if index > len(prs) {
+		return fmt.Errorf("can't apply pull request %d, the update only proposed %d", index, len(prs))
+	}
+	if index > 0 {
+		prs = prs[index-1 : index]
+	}
+	if err := checkOverlap(prs); err != nil {
+		return err
+	}
+
+	for _, pr := range prs {
+		logf(ctx, "Applying %q to %s\n", pr.PRTitle, dir)
+		for _, file := range pr.UpdatedDependencyFiles {
+			if err := applyFile(dir, original(file)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkOverlap fails when pull requests change the same file, since each has the complete file and
+// applying them in turn would lose the changes of all but the last.
+func checkOverlap(prs []model.CreatePullRequest) error {
+	changedBy := map[string]int{}
+	for i, pr := range prs {
+		for _, file := range pr.UpdatedDependencyFiles {
+			name := path.Join("/", file.Directory, file.Name)
+			if j, ok := changedBy[name]; ok && j != i {
+				return fmt.Errorf("%q and %q both change %s, pass the number of the pull request to apply", prs[j].PRTitle, pr.PRTitle, name)
+			}
+			changedBy[name] = i
+		}
+	}
+	return nil
+}
+
+// applyFile writes, deletes or links a single updated file relative to the repository root dir.
+func applyFile(dir string, file model.DependencyFile) error {
+	filename, err := repoPath(dir, file)
+	if err != nil {
+		return err
+	}
+	// the file is changed in its real directory, so a symlinked directory can't take it out of the repository
+	parent, err := resolveInRepo(dir, filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	filename = filepath.Join(parent, filepath.Base(filename))
+
+	if file.Deleted || file.Operation == "delete" {
+		if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to delete %s: %w", filename, err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", filename, err)
+	}
+
+	if file.SymlinkTarget != "" {
+		target := file.SymlinkTarget
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(parent, filepath.FromSlash(target))
+		}
+		if _, err := resolveInRepo(dir, target); err != nil {
+			return fmt.Errorf("can't link %s to %s: %w", filename, file.SymlinkTarget, err)
+		}
+		if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to replace %s: %w", filename, err)
+		}
+		if err := os.Symlink(file.SymlinkTarget, filename); err != nil {
+			return fmt.Errorf("failed to link %s: %w", filename, err)
+		}
+		return nil
+	}
+
+	// an existing symlink is written through, as long as it stays in the repository
+	if filename, err = resolveInRepo(dir, filename); err != nil {
+		return err
+	}
+	content, err := fileContent(file)
+	if err != nil {
+		return fmt.Errorf("failed to apply %s: %w", filename, err)
+	}
+	perm, err := fileMode(filename, file.Mode)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, content, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	// WriteFile doesn't change the permissions of existing files
+	if err := os.Chmod(filename, perm); err != nil {
+		return fmt.Errorf("failed to set mode of %s: %w", filename, err)
+	}
+	return nil
+}
+
+// repoPath is the path of the file on disk. The name is cleaned as if it were rooted at the repository,
+// so it can't point outside of it lexically, resolveInRepo checks the symlinks along the way.
+func repoPath(dir string, file model.DependencyFile) (string, error) {
+	name := path.Join("/", file.Directory, file.Name)
+	if name == "/" {
+		return "", fmt.Errorf("file in %q has no name", file.Directory)
+	}
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
+
+// resolveInRepo returns the real location of name, which doesn't have to exist yet, following the
+// symlinks in it. It fails when the real location is outside of the repository root dir.
+func resolveInRepo(dir, name string) (string, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	rel, ok := relativeTo(root, name)
+	if !ok {
+		if rel, ok = relativeTo(filepath.Clean(dir), name); !ok {
+			return "", fmt.Errorf("%s is outside of the repository", name)
+		}
+	}
+
+	resolved := root
+	remaining := strings.Split(rel, string(filepath.Separator))
+	for links := 0; len(remaining) > 0; {
+		next := filepath.Join(resolved, remaining[0])
+		remaining = remaining[1:]
+		if _, ok := relativeTo(root, next); !ok {
+			return "", fmt.Errorf("%s is outside of the repository", name)
+		}
+		info, err := os.Lstat(next)
+		if errors.Is(err, fs.ErrNotExist) {
+			// the rest will be created as regular directories and files
+			resolved = next
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if links++; links > 255 {
+			return "", fmt.Errorf("too many levels of symbolic links in %s", name)
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(resolved, target)
+		}
+		targetRel, ok := relativeTo(root, target)
+		if !ok {
+			return "", fmt.Errorf("%s links outside of the repository", next)
+		}
+		// continue from the root with the link's target in place of the link
+		resolved = root
+		remaining = append(strings.Split(targetRel, string(filepath.Separator)), remaining...)
+	}
+	return resolved, nil
+}
+
+// relativeTo returns name relative to root, and whether it's inside of root.
+func relativeTo(root, name string) (string, bool) {
+	rel, err := filepath.Rel(root, filepath.Clean(name))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// fileContent decodes the file's content.
+func fileContent(file model.DependencyFile) ([]byte, error) {
+	switch file.ContentEncoding {
+	case "", "utf-8":
+		return []byte(file.Content), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(file.Content)
+	case "sha256":
+		return nil, fmt.Errorf("the content was replaced with a hash and isn't available")
+	default:
+		return nil, fmt.Errorf("unknown content encoding %q", file.ContentEncoding)
+	}
+}
+
+// fileMode converts a git file mode like 100755 into permissions, keeping the permissions of an
+// existing file when the updater didn't send a mode.
+func fileMode(filename, mode string) (os.FileMode, error) {
+	if mode == "" {
+		if info, err := os.Stat(filename); err == nil {
+			return info.Mode().Perm(), nil
+		}
+		return 0644, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mode %q for %s", mode, filename)
+	}
+	if m&0111 != 0 {
+		return 0755, nil
+	}
+	return 0644, nil
+}
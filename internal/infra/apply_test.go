@@ -0,0 +1,165 @@
+package infra
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+func Test_applyPullRequests(t *testing.T) {
+	identity := func(file model.DependencyFile) model.DependencyFile { return file }
+
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "obsolete.txt"), []byte("bye\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	outputs := []model.Output{
+		{Type: "update_dependency_list"},
+		{Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+			PRTitle: "first",
+			UpdatedDependencyFiles: []model.DependencyFile{
+				{Directory: "/", Name: "go.mod", Content: "module new\n", ContentEncoding: "utf-8"},
+				{Directory: "/", Name: "obsolete.txt", Deleted: true, Operation: "delete"},
+				{Directory: "/tools", Name: "run.sh", Content: base64.StdEncoding.EncodeToString([]byte("#!/bin/sh\n")), ContentEncoding: "base64", Mode: "100755"},
+				{Directory: "/", Name: "link", SymlinkTarget: "go.mod"},
+			},
+		}}},
+		{Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+			PRTitle: "second",
+			UpdatedDependencyFiles: []model.DependencyFile{
+				{Directory: "/", Name: "go.mod", Content: "module second\n", ContentEncoding: "utf-8"},
+			},
+		}}},
+	}
+
+	t.Run("applies a single pull request", func(t *testing.T) {
+		dir := setup(t)
+		if err := applyPullRequests(context.Background(), dir, 1, outputs, identity); err != nil {
+			t.Fatal(err)
+		}
+
+		if data, _ := os.ReadFile(filepath.Join(dir, "go.mod")); string(data) != "module new\n" {
+			t.Errorf("expected go.mod to be updated, got %q", data)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "obsolete.txt")); !os.IsNotExist(err) {
+			t.Errorf("expected obsolete.txt to be deleted")
+		}
+		info, err := os.Stat(filepath.Join(dir, "tools", "run.sh"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0755 {
+			t.Errorf("expected run.sh to be executable, got %v", info.Mode())
+		}
+		if target, _ := os.Readlink(filepath.Join(dir, "link")); target != "go.mod" {
+			t.Errorf("expected link to point to go.mod, got %q", target)
+		}
+	})
+
+	t.Run("applies all pull requests", func(t *testing.T) {
+		dir := setup(t)
+		separate := []model.Output{outputs[1], {Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+			PRTitle:                "third",
+			UpdatedDependencyFiles: []model.DependencyFile{{Directory: "/", Name: "go.sum", Content: "sum\n"}},
+		}}}}
+		if err := applyPullRequests(context.Background(), dir, 0, separate, identity); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "go.mod")); string(data) != "module new\n" {
+			t.Errorf("expected go.mod to be updated by the first PR, got %q", data)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "go.sum")); string(data) != "sum\n" {
+			t.Errorf("expected go.sum to be written by the other PR, got %q", data)
+		}
+	})
+
+	t.Run("rejects pull requests changing the same file", func(t *testing.T) {
+		dir := setup(t)
+		err := applyPullRequests(context.Background(), dir, 0, outputs, identity)
+		if err == nil || !strings.Contains(err.Error(), `"first" and "second" both change /go.mod`) {
+			t.Errorf("expected an error naming the pull requests, got %v", err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "go.mod")); string(data) != "module old\n" {
+			t.Errorf("expected nothing to be applied, got %q", data)
+		}
+	})
+
+	t.Run("rejects an index that doesn't exist", func(t *testing.T) {
+		if err := applyPullRequests(context.Background(), setup(t), 3, outputs, identity); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("keeps files inside of the repo", func(t *testing.T) {
+		dir := setup(t)
+		if err := applyFile(dir, model.DependencyFile{Directory: "/../..", Name: "escape.txt", Content: "x"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "escape.txt")); err != nil {
+			t.Errorf("expected the file to be written inside the repo: %v", err)
+		}
+	})
+
+	t.Run("doesn't write through symlinks outside of the repo", func(t *testing.T) {
+		dir, outside := setup(t), t.TempDir()
+
+		err := applyFile(dir, model.DependencyFile{Directory: "/", Name: "out", SymlinkTarget: outside})
+		if err == nil {
+			t.Error("expected an error for an absolute link outside of the repo")
+		}
+		err = applyFile(dir, model.DependencyFile{Directory: "/sub", Name: "up", SymlinkTarget: "../.."})
+		if err == nil {
+			t.Error("expected an error for a relative link outside of the repo")
+		}
+
+		// a symlink that's already in the checkout
+		if err = os.Symlink(outside, filepath.Join(dir, "vendor")); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Symlink(filepath.Join(outside, "file"), filepath.Join(dir, "file")); err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range []model.DependencyFile{
+			{Directory: "/vendor", Name: "escape.txt", Content: "x"},
+			{Directory: "/", Name: "file", Content: "x"},
+			{Directory: "/vendor", Name: "escape.txt", Deleted: true},
+		} {
+			if err = applyFile(dir, file); err == nil {
+				t.Errorf("expected an error writing %s/%s", file.Directory, file.Name)
+			}
+		}
+		if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+			t.Errorf("expected nothing to be written outside of the repo, got %v", entries)
+		}
+
+		// links inside of the repo still work
+		if err = applyFile(dir, model.DependencyFile{Directory: "/", Name: "lib", SymlinkTarget: "tools"}); err != nil {
+			t.Fatal(err)
+		}
+		if err = applyFile(dir, model.DependencyFile{Directory: "/lib", Name: "run.sh", Content: "x"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = os.Stat(filepath.Join(dir, "tools", "run.sh")); err != nil {
+			t.Errorf("expected the file to be written through the link: %v", err)
+		}
+	})
+
+	t.Run("needs the original content", func(t *testing.T) {
+		err := applyFile(setup(t), model.DependencyFile{Directory: "/", Name: "go.mod", Content: "abc", ContentEncoding: "sha256"})
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+// createBranches creates a branch in the git repository at dir for each create_pull_request, with a
+// commit of the updated files on top of HEAD. The commits are built with a temporary index, so the
+// working tree, the real index and any remotes are left alone.
+func createBranches(ctx context.Context, dir string, packageManager string, outputs []model.Output, original func(model.DependencyFile) model.DependencyFile) error {
+	prs, err := createdPullRequests(outputs)
+	if err != nil {
+		return err
+	}
+	if len(prs) == 0 {
+		return nil
+	}
+
+	base, err := git(ctx, dir, nil, "", "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to find HEAD of %s: %w", dir, err)
+	}
+
+	tmp, err := os.MkdirTemp("", "dependabot-branches")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for i, pr := range prs {
+		env := []string{
+			"GIT_INDEX_FILE=" + filepath.Join(tmp, fmt.Sprintf("index-%d", i)),
+			"GIT_AUTHOR_NAME=dependabot",
+			"GIT_AUTHOR_EMAIL=dependabot@github.com",
+			"GIT_COMMITTER_NAME=dependabot",
+			"GIT_COMMITTER_EMAIL=dependabot@github.com",
+		}
+		if _, err = git(ctx, dir, env, "", "read-tree", base); err != nil {
+			return err
+		}
+		for _, file := range pr.UpdatedDependencyFiles {
+			if err = stageFile(ctx, dir, env, base, original(file)); err != nil {
+				return err
+			}
+		}
+		tree, err := git(ctx, dir, env, "", "write-tree")
+		if err != nil {
+			return err
+		}
+
+		message := firstNonEmpty(pr.CommitMessage, pr.PRTitle)
+		if pr.PRBody != "" {
+			message += "\n\n" + pr.PRBody
+		}
+		commit, err := git(ctx, dir, env, message, "commit-tree", tree, "-p", base, "-F", "-")
+		if err != nil {
+			return err
+		}
+
+		branch := branchName(packageManager, pr.PRTitle, i+1)
+		// an existing branch may have the user's own work on it, so it's only left as is
+		if existing, err := git(ctx, dir, nil, "", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+			if sameChange(ctx, dir, existing, tree, base) {
+				logf(ctx, "Branch %s for %q is up to date\n", branch, pr.PRTitle)
+			} else {
+				logf(ctx, "Warning: not creating branch %s for %q, it already exists and points elsewhere\n", branch, pr.PRTitle)
+			}
+			continue
+		}
+		if _, err = git(ctx, dir, nil, "", "branch", branch, commit); err != nil {
+			return err
+		}
+		logf(ctx, "Created branch %s for %q\n", branch, pr.PRTitle)
+	}
+	return nil
+}
+
+// sameChange is whether the commit has the tree on top of base, like the branch of a previous run.
+func sameChange(ctx context.Context, dir, commit, tree, base string) bool {
+	existingTree, err := git(ctx, dir, nil, "", "rev-parse", commit+"^{tree}")
+	if err != nil {
+		return false
+	}
+	parents, err := git(ctx, dir, nil, "", "rev-list", "--parents", "-n", "1", commit)
+	return err == nil && existingTree == tree && parents == commit+" "+base
+}
+
+// stageFile adds the updated file to the temporary index.
+func stageFile(ctx context.Context, dir string, env []string, base string, file model.DependencyFile) error {
+	name := strings.TrimPrefix(path.Join("/", file.Directory, file.Name), "/")
+	if file.Deleted || file.Operation == "delete" {
+		_, err := git(ctx, dir, env, "", "update-index", "--force-remove", "--", name)
+		return err
+	}
+
+	var content, mode string
+	if file.SymlinkTarget != "" {
+		content, mode = file.SymlinkTarget, "120000"
+	} else {
+		data, err := fileContent(file)
+		if err != nil {
+			return fmt.Errorf("failed to commit %s: %w", name, err)
+		}
+		content, mode = string(data), file.Mode
+		if mode == "" {
+			mode = existingMode(ctx, dir, base, name)
+		}
+	}
+
+	blob, err := git(ctx, dir, env, content, "hash-object", "-w", "--stdin")
+	if err != nil {
+		return err
+	}
+	_, err = git(ctx, dir, env, "", "update-index", "--add", "--cacheinfo", fmt.Sprintf("%s,%s,%s", mode, blob, name))
+	return err
+}
+
+// existingMode returns the mode of the file in the base commit, or a regular file's mode for new files.
+func existingMode(ctx context.Context, dir, base, name string) string {
+	out, err := git(ctx, dir, nil, "", "ls-tree", base, "--", name)
+	if err != nil || out == "" {
+		return "100644"
+	}
+	mode, _, _ := strings.Cut(out, " ")
+	return mode
+}
+
+// branchName is similar to the branches Dependabot creates, e.g. dependabot/go_modules/bump-rsc-io-quote-v3-from-3-0-0-to-3-1-0
+func branchName(packageManager, title string, n int) string {
+	slug := strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if slug == "" {
+		slug = fmt.Sprintf("pull-request-%d", n)
+	}
+	return fmt.Sprintf("dependabot/%s/%s", packageManager, slug)
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, env []string, stdin string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
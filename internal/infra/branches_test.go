@@ -0,0 +1,98 @@
+package infra
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+func Test_createBranches(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	identity := func(file model.DependencyFile) model.DependencyFile { return file }
+
+	dir := t.TempDir()
+	env := []string{
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	}
+	run := func(args ...string) string {
+		out, err := git(ctx, dir, env, "", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	run("init", "--quiet")
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("require a v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", ".")
+	run("commit", "--quiet", "-m", "initial commit")
+	head := run("rev-parse", "HEAD")
+
+	outputs := []model.Output{{Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+		PRTitle:       "Bump a from v1 to v2",
+		PRBody:        "Bumps a.",
+		CommitMessage: "Bump a from v1 to v2",
+		UpdatedDependencyFiles: []model.DependencyFile{
+			{Directory: "/", Name: "go.mod", Content: "require a v2\n", ContentEncoding: "utf-8"},
+			{Directory: "/", Name: "old.txt", Deleted: true},
+			{Directory: "/scripts", Name: "run.sh", Content: "#!/bin/sh\n", Mode: "100755"},
+		},
+	}}}}
+
+	if err := createBranches(ctx, dir, "go_modules", outputs, identity); err != nil {
+		t.Fatal(err)
+	}
+
+	const branch = "dependabot/go_modules/bump-a-from-v1-to-v2"
+	if parent := run("rev-parse", branch+"^"); parent != head {
+		t.Errorf("expected the branch to be based on HEAD, got %v", parent)
+	}
+	if message := run("log", "-1", "--format=%B", branch); message != "Bump a from v1 to v2\n\nBumps a." {
+		t.Errorf("unexpected commit message: %q", message)
+	}
+	if files := run("ls-tree", "-r", "--name-only", branch); files != "go.mod\nscripts/run.sh" {
+		t.Errorf("unexpected files: %q", files)
+	}
+	if content := run("show", branch+":go.mod"); content != "require a v2" {
+		t.Errorf("unexpected content: %q", content)
+	}
+	if mode := existingMode(ctx, dir, branch, "scripts/run.sh"); mode != "100755" {
+		t.Errorf("expected run.sh to be executable, got %v", mode)
+	}
+
+	// running again leaves the branch as is, whether it's from the last run or the user's
+	created := run("rev-parse", branch)
+	if err := createBranches(ctx, dir, "go_modules", outputs, identity); err != nil {
+		t.Fatal(err)
+	}
+	if current := run("rev-parse", branch); current != created {
+		t.Errorf("expected the up to date branch to be kept")
+	}
+	run("branch", "--force", branch, head)
+	if err := createBranches(ctx, dir, "go_modules", outputs, identity); err != nil {
+		t.Fatal(err)
+	}
+	if current := run("rev-parse", branch); current != head {
+		t.Errorf("expected the existing branch not to be reset")
+	}
+
+	// the working tree and HEAD are untouched
+	if current := run("rev-parse", "HEAD"); current != head {
+		t.Errorf("expected HEAD to be unchanged")
+	}
+	if status := run("status", "--porcelain"); status != "" {
+		t.Errorf("expected a clean working tree, got %q", status)
+	}
+}
@@ -0,0 +1,106 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dependabot/cli/internal/model"
+	"github.com/dependabot/cli/internal/server"
+)
+
+// updaterVersionLabel is the OCI label holding the dependabot-core release an updater image was built from.
+const updaterVersionLabel = "org.opencontainers.image.version"
+
+// updaterChange is a change to the updater the CLI has to model, keyed by the release that introduced it.
+type updaterChange struct {
+	Since     string
+	Endpoints []string
+	JobFields []string
+}
+
+// updaterChanges is the compatibility table between the updater and the CLI. When the updater starts
+// calling a new endpoint or reading a new job field, add it here so that users running an older CLI get
+// a warning rather than a confusing decode error. Each entry needs a comment linking the dependabot-core
+// release or pull request that introduced the change, since a wrong version means false warnings.
+// Entries the CLI models are ignored, so there's no need to remove them once support is added.
+var updaterChanges = []updaterChange{}
+
+// warnUpdaterCompatibility logs a warning for each change in the updater image the CLI doesn't model.
+func warnUpdaterCompatibility(ctx context.Context, labels map[string]string) {
+	for _, warning := range compatibilityWarnings(labels) {
+		logf(ctx, "Warning: %s\n", warning)
+	}
+}
+
+func compatibilityWarnings(labels map[string]string) []string {
+	version, ok := parseVersion(labels[updaterVersionLabel])
+	if !ok {
+		return nil
+	}
+
+	jobFields := modeledJobFields()
+	var warnings []string
+	for _, change := range updaterChanges {
+		since, ok := parseVersion(change.Since)
+		if !ok || compareVersions(version, since) < 0 {
+			continue
+		}
+		for _, endpoint := range change.Endpoints {
+			if !slices.Contains(server.Endpoints, endpoint) {
+				warnings = append(warnings, fmt.Sprintf("updater %s may call the %s endpoint which this version of the CLI doesn't support, consider upgrading the CLI", labels[updaterVersionLabel], endpoint))
+			}
+		}
+		for _, field := range change.JobFields {
+			if !jobFields[field] {
+				warnings = append(warnings, fmt.Sprintf("updater %s expects the %s job field which this version of the CLI doesn't support, consider upgrading the CLI", labels[updaterVersionLabel], field))
+			}
+		}
+	}
+	return warnings
+}
+
+// modeledJobFields returns the job fields the CLI passes to the updater.
+func modeledJobFields() map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(model.Job{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[name] = true
+	}
+	return fields
+}
+
+// parseVersion parses versions like v0.250.0 or 0.250.0-rc1 into major, minor and patch.
+func parseVersion(s string) ([3]int, bool) {
+	var version [3]int
+	s = strings.TrimPrefix(s, "v")
+	s, _, _ = strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return version, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return version, false
+		}
+		version[i] = n
+	}
+	return version, true
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
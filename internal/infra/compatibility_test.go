@@ -0,0 +1,40 @@
+package infra
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_compatibilityWarnings(t *testing.T) {
+	original := updaterChanges
+	t.Cleanup(func() { updaterChanges = original })
+	updaterChanges = []updaterChange{
+		{Since: "0.100.0", Endpoints: []string{"create_pull_request", "record_something_new"}},
+		{Since: "0.200.0", JobFields: []string{"package-manager", "something-new"}},
+	}
+
+	t.Run("no version label", func(t *testing.T) {
+		if warnings := compatibilityWarnings(nil); len(warnings) != 0 {
+			t.Errorf("expected no warnings, got %v", warnings)
+		}
+	})
+
+	t.Run("older updater", func(t *testing.T) {
+		warnings := compatibilityWarnings(map[string]string{updaterVersionLabel: "v0.99.9"})
+		if len(warnings) != 0 {
+			t.Errorf("expected no warnings, got %v", warnings)
+		}
+	})
+
+	t.Run("only warns about what isn't modeled", func(t *testing.T) {
+		warnings := compatibilityWarnings(map[string]string{updaterVersionLabel: "0.150.0"})
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "record_something_new endpoint") {
+			t.Errorf("unexpected warnings: %v", warnings)
+		}
+
+		warnings = compatibilityWarnings(map[string]string{updaterVersionLabel: "v0.200.0"})
+		if len(warnings) != 2 || !strings.Contains(warnings[1], "something-new job field") {
+			t.Errorf("unexpected warnings: %v", warnings)
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package infra
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/cgi"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+// gitServerHost is the host the updater clones a --git-repo from, the proxy resolves it to the host machine.
+const gitServerHost = "git.dependabot.test"
+
+// gitServerCertPath is where the proxy finds the CA of the git server, update-ca-certificates picks it up.
+const gitServerCertPath = "/usr/local/share/ca-certificates/dependabot-git-server.crt"
+
+// gitServicePaths are the parts of a smart HTTP URL that follow the repository name.
+var gitServicePaths = []string{"/info/", "/HEAD", "/objects/", "/git-upload-pack", "/git-receive-pack"}
+
+// gitServer serves a bare repository over git smart HTTP, so the updater's fetch_files step clones it as
+// if it were on a real git host.
+type gitServer struct {
+	server *http.Server
+	port   int
+	dir    string
+	// certPath is the CA that signed the server's certificate
+	certPath string
+	// token is the password the proxy authenticates with, since on Linux the server listens on all interfaces
+	token string
+}
+
+func newGitServer(ctx context.Context, repo string) (*gitServer, error) {
+	repo, err := filepath.Abs(repo)
+	if err != nil {
+		return nil, err
+	}
+	git, err := exec.LookPath("git")
+	if err != nil {
+		return nil, fmt.Errorf("serving a git repository requires git: %w", err)
+	}
+	out, err := exec.Command(git, "-C", repo, "rev-parse", "--is-bare-repository").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "true" {
+		return nil, fmt.Errorf("%s is not a bare git repository", repo)
+	}
+
+	caPEM, cert, err := generateServerCert(gitServerHost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate git server cert: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "dependabot-git-server")
+	if err != nil {
+		return nil, err
+	}
+	certPath := filepath.Join(dir, "ca.crt")
+	if err = os.WriteFile(certPath, []byte(caPEM), 0644); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	// the proxy reaches the host through host-gateway, which isn't loopback on Linux, so anyone who can
+	// reach the machine can reach the server and the token keeps them from cloning the repository
+	host := "127.0.0.1"
+	if runtime.GOOS == "linux" {
+		host = "0.0.0.0"
+	}
+	l, err := net.Listen("tcp", host+":0")
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	server := &http.Server{
+		Handler:           gitHandler(ctx, git, repo, token),
+		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+	go func() {
+		if err := server.ServeTLS(l, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logf(ctx, "git server failed: %v\n", err)
+		}
+	}()
+
+	return &gitServer{
+		server:   server,
+		port:     l.Addr().(*net.TCPAddr).Port,
+		dir:      dir,
+		certPath: certPath,
+		token:    token,
+	}, nil
+}
+
+// host is the hostname of the job's source while the server is running.
+func (g *gitServer) host() string {
+	return fmt.Sprintf("%s:%d", gitServerHost, g.port)
+}
+
+// route returns a copy of params whose job is cloned from the server, through the proxy which has the
+// server's token as a git_source credential.
+func (g *gitServer) route(params RunParams) RunParams {
+	cred := model.Credential{
+		"type":     "git_source",
+		"host":     gitServerHost,
+		"username": "x-access-token",
+		"password": g.token,
+	}
+	job := *params.Job
+	// the updater needs an API endpoint with the hostname, the calls to it are answered with an error
+	hostname := g.host()
+	apiEndpoint := fmt.Sprintf("https://%s/api/v3/", hostname)
+	job.Source.Hostname = &hostname
+	job.Source.APIEndpoint = &apiEndpoint
+	if len(job.CredentialsMetadata) > 0 {
+		metadata := model.Credential{"type": "git_source", "host": gitServerHost, "username": "x-access-token"}
+		job.CredentialsMetadata = append(append([]model.Credential{}, job.CredentialsMetadata...), metadata)
+	}
+	params.Job = &job
+	params.Creds = append(append([]model.Credential{}, params.Creds...), cred)
+	params.ExtraHosts = append(append([]string{}, params.ExtraHosts...), gitServerHost+":host-gateway")
+	params.gitServerCert = g.certPath
+	return params
+}
+
+func (g *gitServer) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	_ = g.server.Shutdown(ctx)
+	cancel()
+	_ = os.RemoveAll(g.dir)
+}
+
+// gitHandler serves the repository with git http-backend, whatever repository name is requested, to
+// clients with the token as their basic auth password. The repository is read-only, and the provider's
+// API isn't served, which is logged since the updater's error wouldn't say why the call failed.
+func gitHandler(ctx context.Context, git, repo, token string) http.Handler {
+	backend := &cgi.Handler{
+		Path: git,
+		Args: []string{"http-backend"},
+		Env: []string{
+			"GIT_PROJECT_ROOT=" + filepath.Dir(repo),
+			"GIT_HTTP_EXPORT_ALL=1",
+		},
+	}
+	name := "/" + filepath.Base(repo)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, password, _ := r.BasicAuth(); subtle.ConstantTimeCompare([]byte(password), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="dependabot"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		suffix, ok := gitServicePath(r.URL.Path)
+		if !ok {
+			logf(ctx, "The updater called %s %s, --git-repo only serves git clones and not the provider's API\n", r.Method, r.URL.Path)
+			http.Error(w, "--git-repo only serves git clones, not the provider's API", http.StatusNotImplemented)
+			return
+		}
+		if strings.HasSuffix(suffix, "/git-receive-pack") || r.URL.Query().Get("service") == "git-receive-pack" {
+			http.Error(w, "the repository is read-only", http.StatusForbidden)
+			return
+		}
+		r.URL.Path = name + suffix
+		backend.ServeHTTP(w, r)
+	})
+}
+
+// gitServicePath returns the part of the path after the repository name, e.g. /info/refs.
+func gitServicePath(path string) (string, bool) {
+	start := -1
+	for _, service := range gitServicePaths {
+		if i := strings.Index(path, service); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+	return path[start:], true
+}
+
+// generateToken returns a random token for a single run of the server.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate git server token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// generateServerCert returns a new CA, and a certificate for host signed by it.
+func generateServerCert(host string) (string, tls.Certificate, error) {
+	caKey, _, err := generateKey()
+	if err != nil {
+		return "", tls.Certificate{}, err
+	}
+	caPEM, err := generateCert(caKey)
+	if err != nil {
+		return "", tls.Certificate{}, err
+	}
+	block, _ := pem.Decode([]byte(caPEM))
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", tls.Certificate{}, err
+	}
+
+	key, pemKey, err := generateKey()
+	if err != nil {
+		return "", tls.Certificate{}, err
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    caCert.NotBefore,
+		NotAfter:     caCert.NotAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, caCert, key.Public(), caKey)
+	if err != nil {
+		return "", tls.Certificate{}, err
+	}
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	cert, err := tls.X509KeyPair(pemCert, []byte(pemKey))
+	return caPEM, cert, err
+}
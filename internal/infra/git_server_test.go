@@ -0,0 +1,173 @@
+package infra
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+func Test_gitServicePath(t *testing.T) {
+	tests := []struct {
+		path   string
+		suffix string
+		ok     bool
+	}{
+		{"/rsc/quote/info/refs", "/info/refs", true},
+		{"/rsc/quote.git/git-upload-pack", "/git-upload-pack", true},
+		{"/rsc/quote/objects/info/packs", "/objects/info/packs", true},
+		{"/rsc/quote/HEAD", "/HEAD", true},
+		{"/api/v3/repos/rsc/quote", "", false},
+	}
+	for _, tt := range tests {
+		suffix, ok := gitServicePath(tt.path)
+		if suffix != tt.suffix || ok != tt.ok {
+			t.Errorf("gitServicePath(%q) = %q, %v, want %q, %v", tt.path, suffix, ok, tt.suffix, tt.ok)
+		}
+	}
+}
+
+func Test_gitHandler(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	env := []string{
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	}
+	run := func(dir string, args ...string) string {
+		out, err := git(ctx, dir, env, "", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	work := t.TempDir()
+	run(work, "init", "--quiet", "--initial-branch", "main")
+	if err := os.WriteFile(filepath.Join(work, "go.mod"), []byte("require a v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(work, "add", ".")
+	run(work, "commit", "--quiet", "-m", "first")
+	bare := filepath.Join(t.TempDir(), "fixture.git")
+	run(work, "clone", "--quiet", "--bare", work, bare)
+
+	srv := httptest.NewServer(gitHandler(ctx, gitPath, bare, "token"))
+	defer srv.Close()
+	authenticated := strings.Replace(srv.URL, "http://", "http://x-access-token:token@", 1)
+
+	t.Run("clones under any repository name", func(t *testing.T) {
+		clone := filepath.Join(t.TempDir(), "clone")
+		run(work, "clone", "--quiet", "--depth", "1", authenticated+"/rsc/quote", clone)
+		if data, err := os.ReadFile(filepath.Join(clone, "go.mod")); err != nil || string(data) != "require a v1\n" {
+			t.Errorf("unexpected clone %q %v", data, err)
+		}
+	})
+
+	t.Run("requires the token", func(t *testing.T) {
+		for _, url := range []string{srv.URL, strings.Replace(srv.URL, "http://", "http://x-access-token:wrong@", 1)} {
+			resp, err := http.Get(url + "/rsc/quote/info/refs?service=git-upload-pack")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("is read-only", func(t *testing.T) {
+		resp, err := http.Get(authenticated + "/rsc/quote/info/refs?service=git-receive-pack")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, resp.StatusCode)
+		}
+	})
+
+	t.Run("doesn't serve the API", func(t *testing.T) {
+		resp, err := http.Get(authenticated + "/api/v3/repos/rsc/quote/contents/go.mod")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotImplemented {
+			t.Errorf("expected status code %d, got %d", http.StatusNotImplemented, resp.StatusCode)
+		}
+	})
+
+	t.Run("serves over TLS", func(t *testing.T) {
+		if _, err := newGitServer(ctx, work); err == nil || !strings.Contains(err.Error(), "not a bare git repository") {
+			t.Errorf("expected a non-bare repository to be rejected, got %v", err)
+		}
+
+		server, err := newGitServer(ctx, bare)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Close()
+
+		ca, err := os.ReadFile(server.certPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots := x509.NewCertPool()
+		roots.AppendCertsFromPEM(ca)
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: gitServerHost, MinVersion: tls.VersionTLS12},
+		}}
+		request, err := http.NewRequest("GET", strings.Replace(server.host(), gitServerHost, "https://127.0.0.1", 1)+"/rsc/quote/info/refs?service=git-upload-pack", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.SetBasicAuth("x-access-token", server.token)
+		resp, err := client.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status %v", resp.Status)
+		}
+	})
+}
+
+func Test_gitServer_route(t *testing.T) {
+	server := &gitServer{port: 1234, certPath: "/tmp/ca.crt", token: "token"}
+	params := RunParams{
+		Job:        &model.Job{Source: model.Source{Provider: "github", Repo: "rsc/quote"}},
+		Creds:      []model.Credential{{"type": "npm_registry"}},
+		ExtraHosts: []string{"example.com:127.0.0.1"},
+	}
+
+	routed := server.route(params)
+	if *routed.Job.Source.Hostname != "git.dependabot.test:1234" || *routed.Job.Source.APIEndpoint != "https://git.dependabot.test:1234/api/v3/" {
+		t.Errorf("unexpected source %v %v", *routed.Job.Source.Hostname, *routed.Job.Source.APIEndpoint)
+	}
+	if len(routed.ExtraHosts) != 2 || routed.ExtraHosts[1] != "git.dependabot.test:host-gateway" {
+		t.Errorf("unexpected extra hosts %v", routed.ExtraHosts)
+	}
+	if routed.gitServerCert != "/tmp/ca.crt" {
+		t.Errorf("unexpected cert %v", routed.gitServerCert)
+	}
+	if len(routed.Creds) != 2 || routed.Creds[1]["host"] != gitServerHost || routed.Creds[1]["password"] != "token" {
+		t.Errorf("expected a git_source credential for the server, got %v", routed.Creds)
+	}
+	if params.Job.Source.Hostname != nil || len(params.ExtraHosts) != 1 || len(params.Creds) != 1 {
+		t.Error("expected the original params to be unchanged")
+	}
+}
@@ -0,0 +1,76 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// guestBundlePath is where the bundle of a local repository's history is copied to in the updater.
+const guestBundlePath = "/tmp/dependabot-local.bundle"
+
+// localGit is the git state of a local directory that is carried into the updater.
+type localGit struct {
+	// Head is the commit checked out in the directory.
+	Head string
+	// Branch is the checked out branch, empty when HEAD is detached.
+	Branch string
+	// Bundle is a bundle of HEAD's history. It's only created when .git is a file pointing somewhere
+	// else on the host, as it does in worktrees and submodules, since copying it would be useless.
+	Bundle string
+}
+
+// inspectLocalGit reads the git state of dir, creating a bundle in tmp if needed.
+func inspectLocalGit(ctx context.Context, dir, tmp string) (*localGit, error) {
+	info, err := os.Lstat(filepath.Join(dir, ".git"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("can't preserve git history, %s isn't the root of a git repository", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := git(ctx, dir, nil, "", "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("can't preserve git history of %s: %w", dir, err)
+	}
+	local := &localGit{Head: head}
+	// symbolic-ref fails when HEAD is detached
+	local.Branch, _ = git(ctx, dir, nil, "", "symbolic-ref", "--quiet", "--short", "HEAD")
+
+	if !info.IsDir() {
+		local.Bundle = filepath.Join(tmp, "local.bundle")
+		if _, err = git(ctx, dir, nil, "", "bundle", "create", "--quiet", local.Bundle, "HEAD"); err != nil {
+			return nil, fmt.Errorf("failed to bundle git history of %s: %w", dir, err)
+		}
+	}
+	return local, nil
+}
+
+// restoreCommands are the commands that recreate the repository from the bundle in the updater,
+// checking out the same commit and branch without touching the copied working tree.
+func (l *localGit) restoreCommands() []string {
+	commands := []string{"git init --quiet"}
+	if l.Branch != "" {
+		ref := shellQuote("refs/heads/" + l.Branch)
+		commands = append(commands,
+			fmt.Sprintf("git fetch --quiet %s +HEAD:%s", guestBundlePath, ref),
+			"git symbolic-ref HEAD "+ref,
+		)
+	} else {
+		commands = append(commands,
+			fmt.Sprintf("git fetch --quiet %s HEAD", guestBundlePath),
+			"git update-ref --no-deref HEAD "+l.Head,
+		)
+	}
+	return append(commands, "git reset --quiet", "rm "+guestBundlePath)
+}
+
+// shellQuote quotes s for /bin/sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,117 @@
+package infra
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_inspectLocalGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	env := []string{
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	}
+	run := func(dir string, args ...string) string {
+		out, err := git(ctx, dir, env, "", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	repo := t.TempDir()
+	run(repo, "init", "--quiet", "--initial-branch", "main")
+	if err := os.WriteFile(filepath.Join(repo, "go.mod"), []byte("require a v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(repo, "add", ".")
+	run(repo, "commit", "--quiet", "-m", "first")
+	run(repo, "commit", "--quiet", "--allow-empty", "-m", "second")
+	head := run(repo, "rev-parse", "HEAD")
+
+	t.Run("repository", func(t *testing.T) {
+		local, err := inspectLocalGit(ctx, repo, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if local.Head != head || local.Branch != "main" {
+			t.Errorf("unexpected state %+v", local)
+		}
+		if local.Bundle != "" {
+			t.Error("expected the .git directory to be copied rather than bundled")
+		}
+	})
+
+	t.Run("not a repository", func(t *testing.T) {
+		if _, err := inspectLocalGit(ctx, t.TempDir(), t.TempDir()); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("worktree", func(t *testing.T) {
+		worktree := filepath.Join(t.TempDir(), "worktree")
+		run(repo, "worktree", "add", "--quiet", "-b", "feature", worktree)
+		defer run(repo, "worktree", "remove", "--force", worktree)
+
+		local, err := inspectLocalGit(ctx, worktree, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if local.Head != head || local.Branch != "feature" || local.Bundle == "" {
+			t.Fatalf("unexpected state %+v", local)
+		}
+
+		// restore into a copy of the working tree like the updater does
+		guest := t.TempDir()
+		if err := os.WriteFile(filepath.Join(guest, "go.mod"), []byte("require a v1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		script := strings.ReplaceAll(strings.Join(local.restoreCommands(), " && "), guestBundlePath, local.Bundle)
+		script = strings.Replace(script, "rm "+local.Bundle, "true", 1)
+		cmd := exec.Command("/bin/sh", "-c", script)
+		cmd.Dir = guest
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("restore failed: %v: %s", err, out)
+		}
+
+		if got := run(guest, "rev-parse", "HEAD"); got != head {
+			t.Errorf("expected HEAD %v, got %v", head, got)
+		}
+		if got := run(guest, "symbolic-ref", "--short", "HEAD"); got != "feature" {
+			t.Errorf("expected branch feature, got %v", got)
+		}
+		if got := run(guest, "rev-list", "--count", "HEAD"); got != "2" {
+			t.Errorf("expected the history to be preserved, got %v commits", got)
+		}
+		if got := run(guest, "status", "--porcelain"); got != "" {
+			t.Errorf("expected a clean working tree, got %v", got)
+		}
+	})
+
+	t.Run("detached worktree", func(t *testing.T) {
+		worktree := filepath.Join(t.TempDir(), "detached")
+		run(repo, "worktree", "add", "--quiet", "--detach", worktree, "HEAD~1")
+		defer run(repo, "worktree", "remove", "--force", worktree)
+
+		local, err := inspectLocalGit(ctx, worktree, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if local.Branch != "" || local.Head != run(repo, "rev-parse", "HEAD~1") {
+			t.Errorf("unexpected state %+v", local)
+		}
+	})
+}
+
+func Test_shellQuote(t *testing.T) {
+	if got := shellQuote("it's"); got != `'it'\''s'` {
+		t.Errorf("unexpected quoting %v", got)
+	}
+}
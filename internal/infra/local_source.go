@@ -0,0 +1,293 @@
+package infra
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/moby/patternmatcher"
+)
+
+// localSizeWarning is the size of a --local source above which the CLI shows where the size comes from.
+const localSizeWarning = 250 * 1000 * 1000
+
+// ignoreFiles are read in every directory of a --local source. A clone wouldn't contain the files git
+// ignores, so the updater shouldn't see them either, and .dependabotignore can exclude even more.
+var ignoreFiles = []string{".gitignore", ".dependabotignore"}
+
+// localSource describes what is copied from a --local directory into the updater.
+type localSource struct {
+	// Excludes are the patterns to leave out, in the syntax of archive.TarOptions.
+	Excludes []string
+	// Size is the total size of the files that are copied.
+	Size int64
+	// Sizes breaks Size down by top level file or directory.
+	Sizes map[string]int64
+}
+
+// ignoreRule is a line of an ignore file, or an --local-exclude pattern.
+type ignoreRule struct {
+	// pattern is relative to the root, starting with ! when the rule includes what it matches
+	pattern string
+	// dirOnly rules, which end in a slash, only match directories and what's in them
+	dirOnly bool
+	// dirs are the directories the dirOnly rule matched, which archive.TarOptions can't tell apart from files
+	dirs []string
+}
+
+// scanLocalSource reads the ignore files in dir and adds up the size of everything else. The extra
+// patterns use the .gitignore syntax and are relative to dir.
+func scanLocalSource(dir string, extra []string) (*localSource, error) {
+	var rules []ignoreRule
+	for _, line := range extra {
+		rules = append(rules, ignorePattern("", line)...)
+	}
+	// git never ignores its own directory, but it can be excluded explicitly
+	keepGit := true
+	if len(rules) > 0 {
+		m, err := newIgnoreMatcher(rules)
+		if err != nil {
+			return nil, err
+		}
+		if excluded, err := m.excluded(".git", true); err != nil {
+			return nil, err
+		} else if excluded {
+			keepGit = false
+		}
+	}
+
+	source := &localSource{Sizes: map[string]int64{}}
+	var m *ignoreMatcher
+	err := filepath.WalkDir(dir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		inGit := rel == ".git" || strings.HasPrefix(rel, ".git/")
+
+		if rel != "." && d.IsDir() && !inGit {
+			if err := matchDirOnly(rules, rel); err != nil {
+				return err
+			}
+		}
+		if rel != "." && !(inGit && keepGit) && m != nil {
+			excluded, err := m.excluded(rel, d.IsDir())
+			if err != nil {
+				return err
+			}
+			if excluded {
+				// a later negated pattern might include something inside of the directory
+				if d.IsDir() && !m.exclusions() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if d.IsDir() {
+			if inGit {
+				return nil
+			}
+			found, err := readIgnoreFiles(filename, rel)
+			if err != nil {
+				return err
+			}
+			if len(found) > 0 || m == nil {
+				rules = append(rules, found...)
+				if m, err = newIgnoreMatcher(rules); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		top, _, _ := strings.Cut(rel, "/")
+		source.Size += info.Size()
+		source.Sizes[top] += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	for _, rule := range rules {
+		if !rule.dirOnly {
+			source.Excludes = append(source.Excludes, rule.pattern)
+			continue
+		}
+		// what's in the directories is matched by the pattern, the directories themselves by their paths
+		source.Excludes = append(source.Excludes, rule.pattern+"/**")
+		negate := ""
+		if strings.HasPrefix(rule.pattern, "!") {
+			negate = "!"
+		}
+		for _, dir := range rule.dirs {
+			source.Excludes = append(source.Excludes, negate+escapePattern(dir))
+		}
+	}
+	if keepGit {
+		source.Excludes = append(source.Excludes, "!.git", "!.git/**")
+	}
+	return source, nil
+}
+
+// matchDirOnly records the directory in the directory-only rules that match it.
+func matchDirOnly(rules []ignoreRule, rel string) error {
+	for i := range rules {
+		if !rules[i].dirOnly {
+			continue
+		}
+		match, err := patternmatcher.Matches(rel, []string{strings.TrimPrefix(rules[i].pattern, "!")})
+		if err != nil {
+			return fmt.Errorf("invalid ignore pattern: %w", err)
+		}
+		if match {
+			rules[i].dirs = append(rules[i].dirs, rel)
+		}
+	}
+	return nil
+}
+
+// ignoreMatcher matches paths against the rules in order, the last one matching wins as in git. Files
+// only match directory-only rules through the directories they're in.
+type ignoreMatcher struct {
+	dirs, files *patternmatcher.PatternMatcher
+}
+
+func newIgnoreMatcher(rules []ignoreRule) (*ignoreMatcher, error) {
+	var dirs, files []string
+	for _, rule := range rules {
+		dirs = append(dirs, rule.pattern)
+		if rule.dirOnly {
+			files = append(files, rule.pattern+"/**")
+		} else {
+			files = append(files, rule.pattern)
+		}
+	}
+	m := &ignoreMatcher{}
+	var err error
+	if m.dirs, err = patternmatcher.New(dirs); err != nil {
+		return nil, fmt.Errorf("invalid ignore pattern: %w", err)
+	}
+	if m.files, err = patternmatcher.New(files); err != nil {
+		return nil, fmt.Errorf("invalid ignore pattern: %w", err)
+	}
+	return m, nil
+}
+
+func (m *ignoreMatcher) excluded(rel string, isDir bool) (bool, error) {
+	if isDir {
+		return m.dirs.MatchesOrParentMatches(rel)
+	}
+	return m.files.MatchesOrParentMatches(rel)
+}
+
+func (m *ignoreMatcher) exclusions() bool {
+	return m.dirs.Exclusions()
+}
+
+// escapePattern escapes a path so it only matches itself.
+func escapePattern(p string) string {
+	var b strings.Builder
+	for _, c := range p {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// readIgnoreFiles returns the rules of the ignore files in the directory.
+func readIgnoreFiles(dir, rel string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	for _, name := range ignoreFiles {
+		f, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			rules = append(rules, ignorePattern(rel, scanner.Text())...)
+		}
+		f.Close()
+		if err = scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(dir, name), err)
+		}
+	}
+	return rules, nil
+}
+
+// ignorePattern converts a line of a .gitignore in the directory base into a rule, whose pattern is
+// always relative to the root. Patterns without a slash match at any depth, as they do in git.
+func ignorePattern(base, line string) []ignoreRule {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	var negate string
+	if strings.HasPrefix(line, "!") {
+		negate, line = "!", line[1:]
+	}
+	if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	// a trailing slash only matches directories
+	dirOnly := strings.HasSuffix(line, "/")
+	line = strings.TrimRight(line, "/")
+	if line == "" {
+		return nil
+	}
+
+	if base == "." {
+		base = ""
+	}
+	if strings.Contains(line, "/") {
+		return []ignoreRule{{pattern: negate + path.Join(base, strings.TrimPrefix(line, "/")), dirOnly: dirOnly}}
+	}
+	return []ignoreRule{{pattern: negate + path.Join(base, "**", line), dirOnly: dirOnly}}
+}
+
+// warnLocalSize logs the largest parts of a --local source when it's big enough to slow down the run.
+func warnLocalSize(ctx context.Context, dir string, source *localSource) {
+	if source.Size < localSizeWarning {
+		return
+	}
+
+	names := make([]string, 0, len(source.Sizes))
+	for name := range source.Sizes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return source.Sizes[names[i]] > source.Sizes[names[j]]
+	})
+	if len(names) > 10 {
+		names = names[:10]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Warning: copying %s from %s, consider excluding large files with .dependabotignore or --local-exclude. The largest are:\n", units.HumanSize(float64(source.Size)), dir)
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-10s %s\n", units.HumanSize(float64(source.Sizes[name])), name)
+	}
+	logf(ctx, "%s", b.String())
+}
@@ -0,0 +1,135 @@
+package infra
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/docker/docker/pkg/archive"
+)
+
+func Test_ignorePattern(t *testing.T) {
+	tests := []struct {
+		base, line string
+		want       []ignoreRule
+	}{
+		{".", "", nil},
+		{".", "# comment", nil},
+		{".", "node_modules/", []ignoreRule{{pattern: "**/node_modules", dirOnly: true}}},
+		{".", "*.log", []ignoreRule{{pattern: "**/*.log"}}},
+		{".", "/dist", []ignoreRule{{pattern: "dist"}}},
+		{".", "docs/*.md", []ignoreRule{{pattern: "docs/*.md"}}},
+		{".", "!keep.log", []ignoreRule{{pattern: "!**/keep.log"}}},
+		{".", `\#file`, []ignoreRule{{pattern: "**/#file"}}},
+		{"web", "build", []ignoreRule{{pattern: "web/**/build"}}},
+		{"web", "/out/", []ignoreRule{{pattern: "web/out", dirOnly: true}}},
+		{"", "vendor  ", []ignoreRule{{pattern: "**/vendor"}}},
+	}
+	for _, tt := range tests {
+		if got := ignorePattern(tt.base, tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ignorePattern(%q, %q) = %v, want %v", tt.base, tt.line, got, tt.want)
+		}
+	}
+}
+
+func Test_scanLocalSource(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitignore":               "node_modules/\n*.log\n!keep.log\n",
+		".dependabotignore":        "/fixtures\n",
+		".git/config":              "[core]\n",
+		"package.json":             "{}",
+		"keep.log":                 "keep",
+		"debug.log":                "debug",
+		"node_modules/a/index.js":  "module.exports = 1",
+		"fixtures/big.bin":         "0123456789",
+		"web/.gitignore":           "/out\n",
+		"web/package.json":         "{}",
+		"web/out/bundle.js":        "bundle",
+		"web/node_modules/b/b.js":  "b",
+		"web/src/out/component.js": "component",
+		"huge/data.bin":            "0123456789",
+		"tools/node_modules":       "a file, not a directory",
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source, err := scanLocalSource(dir, []string{"huge/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, size := tarredFiles(t, dir, source)
+
+	want := []string{
+		".dependabotignore",
+		".git/config",
+		".gitignore",
+		"keep.log",
+		"package.json",
+		"tools/node_modules",
+		"web/.gitignore",
+		"web/package.json",
+		"web/src/out/component.js",
+	}
+	if !reflect.DeepEqual(copied, want) {
+		t.Errorf("expected to copy %v, got %v", want, copied)
+	}
+	if source.Size != size {
+		t.Errorf("expected a size of %d, got %d", size, source.Size)
+	}
+	if source.Sizes["web"] != int64(len("/out\n{}component")) {
+		t.Errorf("unexpected size of web %d", source.Sizes["web"])
+	}
+
+	t.Run("excludes .git when asked to", func(t *testing.T) {
+		source, err := scanLocalSource(dir, []string{".git", "huge/"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied, size := tarredFiles(t, dir, source)
+		if len(copied) != len(want)-1 || copied[1] != ".gitignore" {
+			t.Errorf("expected to copy %v without .git/config, got %v", want, copied)
+		}
+		if source.Size != size {
+			t.Errorf("expected a size of %d, got %d", size, source.Size)
+		}
+	})
+}
+
+// tarredFiles returns the files archive.TarWithOptions copies from the source and their total size.
+func tarredFiles(t *testing.T, dir string, source *localSource) ([]string, int64) {
+	r, err := archive.TarWithOptions(dir, &archive.TarOptions{ExcludePatterns: source.Excludes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var copied []string
+	var size int64
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			copied = append(copied, header.Name)
+			size += header.Size
+		}
+	}
+	sort.Strings(copied)
+	return copied, size
+}
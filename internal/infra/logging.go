@@ -0,0 +1,27 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+)
+
+// loggerKey is the context key of the logger of a run.
+type loggerKey struct{}
+
+// withLogger returns a context whose messages are logged to w, with the prefix and flags of the standard
+// logger. Runs of the serve and schedule commands each have their own log, which would otherwise miss the
+// CLI's messages and interleave with other jobs on stderr.
+func withLogger(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, loggerKey{}, log.New(w, log.Prefix(), log.Flags()))
+}
+
+// logf logs to the run's logger, or the standard logger outside of a run.
+func logf(ctx context.Context, format string, v ...any) {
+	logger, ok := ctx.Value(loggerKey{}).(*log.Logger)
+	if !ok {
+		logger = log.Default()
+	}
+	_ = logger.Output(2, fmt.Sprintf(format, v...))
+}
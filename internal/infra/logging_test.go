@@ -0,0 +1,18 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func Test_logf(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := withLogger(context.Background(), &buf)
+	logf(ctx, "Applying %q\n", "Bump a")
+
+	if !strings.HasSuffix(buf.String(), "Applying \"Bump a\"\n") {
+		t.Errorf("expected the message in the run's log, got %q", buf.String())
+	}
+}
@@ -0,0 +1,205 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+// notifySignatureHeader holds the HMAC-SHA256 of the body when a secret is set, in the same format as
+// GitHub's X-Hub-Signature-256 so existing webhook receivers can verify it.
+const notifySignatureHeader = "X-Dependabot-Signature-256"
+
+// notifyAttempts is how many times a notification is sent before giving up, notifyBackoff is the delay
+// before the first retry and doubles after each.
+var (
+	notifyAttempts = 3
+	notifyBackoff  = time.Second
+)
+
+// runSummary is the body of the notifications sent at the end of a run.
+type runSummary struct {
+	Job          summaryJob     `json:"job"`
+	Status       string         `json:"status"`
+	ExitCode     int            `json:"exit-code"`
+	UpdaterExit  *int           `json:"updater-exit-code,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	StartedAt    time.Time      `json:"started-at"`
+	Duration     float64        `json:"duration-seconds"`
+	PullRequests summaryPRs     `json:"pull-requests"`
+	JobErrors    []summaryError `json:"job-errors"`
+}
+
+type summaryJob struct {
+	PackageManager string   `json:"package-manager"`
+	Provider       string   `json:"provider"`
+	Repo           string   `json:"repo"`
+	Directory      string   `json:"directory,omitempty"`
+	Directories    []string `json:"directories,omitempty"`
+}
+
+type summaryPRs struct {
+	Created []summaryPR `json:"created"`
+	Updated []summaryPR `json:"updated"`
+	Closed  []summaryPR `json:"closed"`
+}
+
+type summaryPR struct {
+	Title        string             `json:"title,omitempty"`
+	Group        string             `json:"group,omitempty"`
+	Dependencies []model.ExistingPR `json:"dependencies"`
+	Reason       string             `json:"reason,omitempty"`
+}
+
+type summaryError struct {
+	ErrorType    string         `json:"error-type"`
+	ErrorDetails map[string]any `json:"error-details,omitempty"`
+}
+
+func newRunSummary(job *model.Job, outputs []model.Output, started time.Time, updaterExit *int, err error) runSummary {
+	summary := runSummary{
+		Job: summaryJob{
+			PackageManager: job.PackageManager,
+			Provider:       job.Source.Provider,
+			Repo:           job.Source.Repo,
+			Directory:      job.Source.Directory,
+			Directories:    job.Source.Directories,
+		},
+		Status:      "succeeded",
+		UpdaterExit: updaterExit,
+		StartedAt:   started.UTC(),
+		Duration:    time.Since(started).Seconds(),
+		PullRequests: summaryPRs{
+			Created: []summaryPR{},
+			Updated: []summaryPR{},
+			Closed:  []summaryPR{},
+		},
+		JobErrors: []summaryError{},
+	}
+	if err != nil {
+		summary.Status = "failed"
+		summary.ExitCode = 1
+		summary.Error = err.Error()
+	}
+
+	for _, out := range outputs {
+		switch data := out.Expect.Data.(type) {
+		case model.CreatePullRequest:
+			pr := summaryPR{Title: data.PRTitle, Group: groupName(data.DependencyGroup), Dependencies: []model.ExistingPR{}}
+			for _, dep := range data.Dependencies {
+				existing := model.ExistingPR{DependencyName: dep.Name}
+				if dep.Version != nil {
+					existing.DependencyVersion = *dep.Version
+				}
+				pr.Dependencies = append(pr.Dependencies, existing)
+			}
+			summary.PullRequests.Created = append(summary.PullRequests.Created, pr)
+		case model.UpdatePullRequest:
+			summary.PullRequests.Updated = append(summary.PullRequests.Updated, summaryPR{
+				Title:        data.PRTitle,
+				Group:        groupName(data.DependencyGroup),
+				Dependencies: dependencyNames(data.DependencyNames),
+			})
+		case model.ClosePullRequest:
+			summary.PullRequests.Closed = append(summary.PullRequests.Closed, summaryPR{
+				Dependencies: dependencyNames(data.DependencyNames),
+				Reason:       data.Reason,
+			})
+		case model.RecordUpdateJobError:
+			summary.JobErrors = append(summary.JobErrors, summaryError{data.ErrorType, data.ErrorDetails})
+		case model.RecordUpdateJobUnknownError:
+			summary.JobErrors = append(summary.JobErrors, summaryError{data.ErrorType, data.ErrorDetails})
+		}
+	}
+	return summary
+}
+
+func dependencyNames(names []string) []model.ExistingPR {
+	deps := []model.ExistingPR{}
+	for _, name := range names {
+		deps = append(deps, model.ExistingPR{DependencyName: name})
+	}
+	return deps
+}
+
+// notify POSTs the summary to each URL. Failures are logged rather than failing the run.
+func notify(ctx context.Context, urls []string, secret string, summary runSummary) {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		logf(ctx, "Failed to encode the notification: %v\n", err)
+		return
+	}
+	for _, url := range urls {
+		if err := sendNotification(ctx, url, secret, body); err != nil {
+			logf(ctx, "Failed to notify %s: %v\n", url, err)
+		}
+	}
+}
+
+// sendNotification sends the body to the URL, retrying connection errors and server errors.
+func sendNotification(ctx context.Context, url, secret string, body []byte) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	backoff := notifyBackoff
+	var err error
+	for attempt := 1; attempt <= notifyAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		var retry bool
+		retry, err = postNotification(ctx, client, url, secret, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", notifyAttempts, err)
+}
+
+func postNotification(ctx context.Context, client *http.Client, url, secret string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dependabot-cli")
+	if secret != "" {
+		req.Header.Set(notifySignatureHeader, signNotification(secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("unexpected status %s", resp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
+
+// signNotification returns the signature header value, e.g. sha256=4a5b...
+func signNotification(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
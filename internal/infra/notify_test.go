@@ -0,0 +1,139 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+func Test_newRunSummary(t *testing.T) {
+	version := "1.1.0"
+	exit := 0
+	job := &model.Job{PackageManager: "go_modules", Source: model.Source{Provider: "github", Repo: "rsc/quote", Directory: "/"}}
+	outputs := []model.Output{
+		{Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+			PRTitle: "Bump a from 1.0.0 to 1.1.0", Dependencies: []model.Dependency{{Name: "a", Version: &version}},
+		}}},
+		{Type: "update_pull_request", Expect: model.UpdateWrapper{Data: model.UpdatePullRequest{
+			PRTitle: "Bump the tools group", DependencyNames: []string{"b"}, DependencyGroup: map[string]any{"name": "tools"},
+		}}},
+		{Type: "close_pull_request", Expect: model.UpdateWrapper{Data: model.ClosePullRequest{
+			DependencyNames: []string{"c"}, Reason: "up_to_date",
+		}}},
+		{Type: "record_update_job_error", Expect: model.UpdateWrapper{Data: model.RecordUpdateJobError{
+			ErrorType: "dependency_file_not_found",
+		}}},
+		{Type: "mark_as_processed", Expect: model.UpdateWrapper{Data: model.MarkAsProcessed{}}},
+	}
+
+	summary := newRunSummary(job, outputs, time.Now().Add(-time.Minute), &exit, nil)
+	if summary.Status != "succeeded" || summary.ExitCode != 0 || summary.Duration < 60 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	prs := summary.PullRequests
+	if len(prs.Created) != 1 || prs.Created[0].Dependencies[0].DependencyVersion != "1.1.0" {
+		t.Errorf("unexpected created pull requests %+v", prs.Created)
+	}
+	if len(prs.Updated) != 1 || prs.Updated[0].Group != "tools" {
+		t.Errorf("unexpected updated pull requests %+v", prs.Updated)
+	}
+	if len(prs.Closed) != 1 || prs.Closed[0].Reason != "up_to_date" {
+		t.Errorf("unexpected closed pull requests %+v", prs.Closed)
+	}
+	if len(summary.JobErrors) != 1 || summary.JobErrors[0].ErrorType != "dependency_file_not_found" {
+		t.Errorf("unexpected job errors %+v", summary.JobErrors)
+	}
+
+	failed := newRunSummary(job, nil, time.Now(), nil, errors.New("updater exited with code 1"))
+	if failed.Status != "failed" || failed.ExitCode != 1 || failed.Error != "updater exited with code 1" {
+		t.Errorf("unexpected summary %+v", failed)
+	}
+}
+
+func Test_notify(t *testing.T) {
+	defer func(backoff time.Duration) { notifyBackoff = backoff }(notifyBackoff)
+	notifyBackoff = time.Millisecond
+
+	summary := newRunSummary(&model.Job{PackageManager: "go_modules"}, nil, time.Now(), nil, nil)
+
+	t.Run("signs and retries", func(t *testing.T) {
+		var calls atomic.Int32
+		var received runSummary
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			if r.Header.Get(notifySignatureHeader) != signNotification("secret", body) {
+				t.Errorf("unexpected signature %v", r.Header.Get(notifySignatureHeader))
+			}
+			if r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("unexpected content type %v", r.Header.Get("Content-Type"))
+			}
+			_ = json.Unmarshal(body, &received)
+		}))
+		defer srv.Close()
+
+		notify(context.Background(), []string{srv.URL}, "secret", summary)
+		if calls.Load() != 2 {
+			t.Errorf("expected a retry, got %d calls", calls.Load())
+		}
+		if received.Job.PackageManager != "go_modules" || received.Status != "succeeded" {
+			t.Errorf("unexpected notification %+v", received)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		body, _ := json.Marshal(summary)
+		if err := sendNotification(context.Background(), srv.URL, "", body); err == nil {
+			t.Error("expected an error")
+		}
+		if calls.Load() != int32(notifyAttempts) {
+			t.Errorf("expected %d attempts, got %d", notifyAttempts, calls.Load())
+		}
+	})
+
+	t.Run("doesn't retry client errors", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			if r.Header.Get(notifySignatureHeader) != "" {
+				t.Error("expected no signature without a secret")
+			}
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer srv.Close()
+
+		body, _ := json.Marshal(summary)
+		if err := sendNotification(context.Background(), srv.URL, "", body); err == nil {
+			t.Error("expected an error")
+		}
+		if calls.Load() != 1 {
+			t.Errorf("expected 1 attempt, got %d", calls.Load())
+		}
+	})
+}
+
+func Test_signNotification(t *testing.T) {
+	// echo -n '{}' | openssl dgst -sha256 -hmac secret
+	const want = "sha256=77325902caca812dc259733aacd046b73817372c777b8d95b402647474516e13"
+	if got := signNotification("secret", []byte("{}")); got != want {
+		t.Errorf("unexpected signature %v", got)
+	}
+}
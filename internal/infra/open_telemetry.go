@@ -8,7 +8,6 @@ import (
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/moby/moby/client"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
@@ -97,7 +96,7 @@ func NewCollector(ctx context.Context, cli *client.Client, net *Networks, params
 		collector.url = fmt.Sprintf("http://%s:4318", containerInfo.NetworkSettings.Networks[net.noInternetName].IPAddress)
 	} else {
 		// This should only happen during testing, adding a warning in case
-		log.Println("Warning: no-internet network not found")
+		logf(ctx, "Warning: no-internet network not found\n")
 	}
 
 	return collector, nil
@@ -0,0 +1,196 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dependabot/cli/internal/model"
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+)
+
+// originalFiles looks up the content of files before the update, preferring what the updater fetched
+// and falling back to the local directory.
+type originalFiles struct {
+	fetched  map[string][]byte
+	localDir string
+}
+
+func newOriginalFiles(fetched []model.DependencyFile, localDir string) *originalFiles {
+	o := &originalFiles{fetched: map[string][]byte{}, localDir: localDir}
+	for _, file := range fetched {
+		// the fetch phase always sends base64 encoded content
+		content, err := fileContent(model.DependencyFile{Content: file.Content, ContentEncoding: "base64"})
+		if err != nil {
+			continue
+		}
+		o.fetched[path.Join("/", file.Directory, file.Name)] = content
+	}
+	return o
+}
+
+// Get returns the original content of the file, ok is false when the file didn't exist before.
+func (o *originalFiles) Get(file model.DependencyFile) (content []byte, ok bool) {
+	name := path.Join("/", file.Directory, file.Name)
+	if content, ok = o.fetched[name]; ok {
+		return content, true
+	}
+	if o.localDir == "" {
+		return nil, false
+	}
+	content, err := os.ReadFile(filepath.Join(o.localDir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, false
+	}
+	return content, true
+}
+
+// writePatches writes a git-apply compatible patch into dir for each create_pull_request and
+// update_pull_request output.
+func writePatches(ctx context.Context, dir string, outputs []model.Output, original func(model.DependencyFile) model.DependencyFile, originals *originalFiles) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create patches directory: %w", err)
+	}
+
+	var n int
+	for _, out := range outputs {
+		var title, message string
+		var files []model.DependencyFile
+		switch pr := out.Expect.Data.(type) {
+		case model.CreatePullRequest:
+			title, message, files = pr.PRTitle, pr.CommitMessage, pr.UpdatedDependencyFiles
+		case model.UpdatePullRequest:
+			title, message, files = pr.PRTitle, pr.CommitMessage, pr.UpdatedDependencyFiles
+		default:
+			continue
+		}
+		n++
+
+		var patch bytes.Buffer
+		patch.WriteString(firstNonEmpty(message, title))
+		patch.WriteString("\n\n")
+		for _, file := range files {
+			if err := writeFileDiff(ctx, &patch, original(file), originals); err != nil {
+				return err
+			}
+		}
+
+		name := filepath.Join(dir, patchName(n, title))
+		if err := os.WriteFile(name, patch.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write patch: %w", err)
+		}
+		logf(ctx, "Wrote %s\n", name)
+	}
+	return nil
+}
+
+// writeFileDiff writes the diff of a single file in the format git uses.
+func writeFileDiff(ctx context.Context, w *bytes.Buffer, file model.DependencyFile, originals *originalFiles) error {
+	name := strings.TrimPrefix(path.Join("/", file.Directory, file.Name), "/")
+	before, existed := originals.Get(file)
+	deleted := file.Deleted || file.Operation == "delete"
+
+	var after []byte
+	if !deleted {
+		if file.SymlinkTarget != "" {
+			after = []byte(file.SymlinkTarget)
+		} else {
+			var err error
+			if after, err = fileContent(file); err != nil {
+				logf(ctx, "Skipping %s in patch: %v\n", name, err)
+				return nil
+			}
+		}
+	}
+
+	if bytes.IndexByte(before, 0) >= 0 || bytes.IndexByte(after, 0) >= 0 {
+		logf(ctx, "Skipping binary file %s in patch\n", name)
+		return nil
+	}
+
+	from, to := "a/"+name, "b/"+name
+	mode := firstNonEmpty(file.Mode, "100644")
+	if file.SymlinkTarget != "" {
+		mode = "120000"
+	}
+
+	var header strings.Builder
+	fmt.Fprintf(&header, "diff --git a/%s b/%s\n", name, name)
+	switch {
+	case deleted:
+		if !existed {
+			return nil
+		}
+		fmt.Fprintf(&header, "deleted file mode %s\n", mode)
+		to = "/dev/null"
+	case !existed:
+		fmt.Fprintf(&header, "new file mode %s\n", mode)
+		from = "/dev/null"
+	}
+
+	var diff string
+	switch {
+	case deleted:
+		diff = wholeFileDiff(from, to, '-', string(before))
+	case !existed:
+		diff = wholeFileDiff(from, to, '+', string(after))
+	default:
+		edits := myers.ComputeEdits(span.URIFromPath(name), string(before), string(after))
+		diff = fmt.Sprint(gotextdiff.ToUnified(from, to, string(before), edits))
+	}
+	if diff == "" {
+		return nil
+	}
+	w.WriteString(header.String())
+	w.WriteString(diff)
+	return nil
+}
+
+// wholeFileDiff is the diff of an added or deleted file. gotextdiff numbers the empty side of these
+// hunks from 1 rather than 0, which git apply rejects.
+func wholeFileDiff(from, to string, op byte, content string) string {
+	if content == "" {
+		return ""
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+	if op == '+' {
+		fmt.Fprintf(&b, "@@ -0,0 +1,%d @@\n", len(lines))
+	} else {
+		fmt.Fprintf(&b, "@@ -1,%d +0,0 @@\n", len(lines))
+	}
+	for _, line := range lines {
+		b.WriteByte(op)
+		b.WriteString(line)
+	}
+	if !strings.HasSuffix(content, "\n") {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+	return b.String()
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// patchName names patches like git format-patch does, e.g. 0001-bump-rsc-io-quote-v3-from-3-0-0-to-3-1-0.patch
+func patchName(n int, title string) string {
+	slug := strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 52 {
+		slug = strings.TrimRight(slug[:52], "-")
+	}
+	if slug == "" {
+		slug = "pull-request"
+	}
+	return fmt.Sprintf("%04d-%s.patch", n, slug)
+}
@@ -0,0 +1,101 @@
+package infra
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+func Test_writePatches(t *testing.T) {
+	identity := func(file model.DependencyFile) model.DependencyFile { return file }
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "go.sum"), []byte("a v1\nb v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fetched := []model.DependencyFile{{Directory: "/", Name: "go.mod", Content: encode("module m\n\nrequire a v1\n")}}
+
+	outputs := []model.Output{
+		{Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+			PRTitle:       "Bump a from v1 to v2",
+			CommitMessage: "Bump a from v1 to v2",
+			UpdatedDependencyFiles: []model.DependencyFile{
+				{Directory: "/", Name: "go.mod", Content: "module m\n\nrequire a v2\n", ContentEncoding: "utf-8"},
+				{Directory: "/", Name: "go.sum", Content: encode("a v2\nb v1\n"), ContentEncoding: "base64"},
+				{Directory: "/vendor", Name: "modules.txt", Content: "# a v2\n", ContentEncoding: "utf-8"},
+			},
+		}}},
+	}
+
+	dir := t.TempDir()
+	if err := writePatches(context.Background(), dir, outputs, identity, newOriginalFiles(fetched, repo)); err != nil {
+		t.Fatal(err)
+	}
+
+	patch, err := os.ReadFile(filepath.Join(dir, "0001-bump-a-from-v1-to-v2.patch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `Bump a from v1 to v2
+
+diff --git a/go.mod b/go.mod
+--- a/go.mod
++++ b/go.mod
+@@ -1,3 +1,3 @@
+ module m
+ 
+-require a v1
++require a v2
+diff --git a/go.sum b/go.sum
+--- a/go.sum
++++ b/go.sum
+@@ -1,2 +1,2 @@
+-a v1
++a v2
+ b v1
+diff --git a/vendor/modules.txt b/vendor/modules.txt
+new file mode 100644
+--- /dev/null
++++ b/vendor/modules.txt
+@@ -0,0 +1,1 @@
++# a v2
+`
+	if string(patch) != expected {
+		t.Errorf("unexpected patch:\n%s", patch)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(repo, "go.mod"), []byte("module m\n\nrequire a v1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("git", "apply", "--check", filepath.Join(dir, "0001-bump-a-from-v1-to-v2.patch"))
+	cmd.Dir = repo
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("git apply failed: %v\n%s", err, out)
+	}
+}
+
+func Test_patchName(t *testing.T) {
+	tests := []struct {
+		n        int
+		title    string
+		expected string
+	}{
+		{1, "Bump rsc.io/quote/v3 from 3.0.0 to 3.1.0", "0001-bump-rsc-io-quote-v3-from-3-0-0-to-3-1-0.patch"},
+		{12, "", "0012-pull-request.patch"},
+		{2, "Bump the go group across 3 directories with 12 updates, and more", "0002-bump-the-go-group-across-3-directories-with-12-updat.patch"},
+	}
+	for _, test := range tests {
+		if actual := patchName(test.n, test.title); actual != test.expected {
+			t.Errorf("expected %v got %v", test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// prState is a file recording the pull requests of previous runs, so that like the Dependabot service
+// the CLI can tell the updater which pull requests are already open.
+type prState struct {
+	Jobs []*prStateJob `yaml:"jobs"`
+
+	filename string
+}
+
+// prStateJob holds the open pull requests of a repository, directory and package manager.
+type prStateJob struct {
+	Provider       string         `yaml:"provider"`
+	Repo           string         `yaml:"repo"`
+	Directory      string         `yaml:"directory"`
+	PackageManager string         `yaml:"package-manager"`
+	PullRequests   []*prStatePull `yaml:"pull-requests"`
+}
+
+type prStatePull struct {
+	Title        string             `yaml:"title"`
+	Group        string             `yaml:"group,omitempty"`
+	Dependencies []model.ExistingPR `yaml:"dependencies"`
+	CreatedAt    time.Time          `yaml:"created-at"`
+	UpdatedAt    time.Time          `yaml:"updated-at,omitempty"`
+}
+
+// loadPRState reads the state file, which doesn't have to exist yet.
+func loadPRState(filename string) (*prState, error) {
+	state := &prState{filename: filename}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err = yaml.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", filename, err)
+	}
+	return state, nil
+}
+
+// save writes the state file, replacing it atomically so an interrupted run can't corrupt it.
+func (s *prState) save() error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), s.filename); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// find returns the state of the job, creating it if needed.
+func (s *prState) find(job *model.Job) *prStateJob {
+	directory := job.Source.Directory
+	if len(job.Source.Directories) > 0 {
+		directory = strings.Join(job.Source.Directories, ",")
+	}
+	for _, j := range s.Jobs {
+		if j.Provider == job.Source.Provider && j.Repo == job.Source.Repo && j.Directory == directory && j.PackageManager == job.PackageManager {
+			return j
+		}
+	}
+	j := &prStateJob{
+		Provider:       job.Source.Provider,
+		Repo:           job.Source.Repo,
+		Directory:      directory,
+		PackageManager: job.PackageManager,
+	}
+	s.Jobs = append(s.Jobs, j)
+	return j
+}
+
+// populate sets the existing pull requests of the job, unless the job already lists some.
+func (s *prState) populate(ctx context.Context, job *model.Job) {
+	if len(job.ExistingPullRequests) > 0 || len(job.ExistingGroupPullRequests) > 0 {
+		logf(ctx, "The job lists existing pull requests, ignoring the state file\n")
+		return
+	}
+	existing := s.find(job)
+	if len(existing.PullRequests) == 0 {
+		return
+	}
+
+	job.ExistingPullRequests = [][]model.ExistingPR{}
+	job.ExistingGroupPullRequests = []model.ExistingGroupPR{}
+	for _, pr := range existing.PullRequests {
+		if pr.Group != "" {
+			job.ExistingGroupPullRequests = append(job.ExistingGroupPullRequests, model.ExistingGroupPR{
+				DependencyGroupName: pr.Group,
+				Dependencies:        pr.Dependencies,
+			})
+		} else {
+			job.ExistingPullRequests = append(job.ExistingPullRequests, pr.Dependencies)
+		}
+	}
+	logf(ctx, "Found %d open pull requests in the state file\n", len(existing.PullRequests))
+}
+
+// record applies the pull requests the updater created, updated, and closed to the state.
+func (s *prState) record(job *model.Job, outputs []model.Output, now time.Time) {
+	existing := s.find(job)
+	for _, out := range outputs {
+		switch data := out.Expect.Data.(type) {
+		case model.CreatePullRequest:
+			pr := &prStatePull{
+				Title:     data.PRTitle,
+				Group:     groupName(data.DependencyGroup),
+				CreatedAt: now,
+			}
+			for _, dep := range data.Dependencies {
+				existingPR := model.ExistingPR{DependencyName: dep.Name}
+				if dep.Version != nil {
+					existingPR.DependencyVersion = *dep.Version
+				}
+				pr.Dependencies = append(pr.Dependencies, existingPR)
+			}
+			existing.remove(pr.Group, pr.names())
+			existing.PullRequests = append(existing.PullRequests, pr)
+		case model.UpdatePullRequest:
+			if pr := existing.match(groupName(data.DependencyGroup), data.DependencyNames); pr != nil {
+				pr.Title = firstNonEmpty(data.PRTitle, pr.Title)
+				pr.UpdatedAt = now
+			}
+		case model.ClosePullRequest:
+			existing.remove("", data.DependencyNames)
+		}
+	}
+}
+
+// match finds the pull request of the group, or of exactly the named dependencies.
+func (j *prStateJob) match(group string, names []string) *prStatePull {
+	names = sortedNames(names)
+	for _, pr := range j.PullRequests {
+		if group != "" && pr.Group == group {
+			return pr
+		}
+		if group == "" && slices.Equal(pr.names(), names) {
+			return pr
+		}
+	}
+	return nil
+}
+
+func (j *prStateJob) remove(group string, names []string) {
+	if pr := j.match(group, names); pr != nil {
+		j.PullRequests = slices.DeleteFunc(j.PullRequests, func(p *prStatePull) bool { return p == pr })
+	}
+}
+
+func (p *prStatePull) names() []string {
+	var names []string
+	for _, dep := range p.Dependencies {
+		names = append(names, dep.DependencyName)
+	}
+	return sortedNames(names)
+}
+
+func sortedNames(names []string) []string {
+	names = slices.Clone(names)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// groupName returns the name of the dependency group a pull request is for, if any.
+func groupName(group map[string]any) string {
+	name, _ := group["name"].(string)
+	return name
+}
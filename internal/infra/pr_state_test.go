@@ -0,0 +1,124 @@
+package infra
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+func Test_prState(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.yml")
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	version := func(v string) *string { return &v }
+	newJob := func() *model.Job {
+		return &model.Job{
+			PackageManager: "go_modules",
+			Source:         model.Source{Provider: "github", Repo: "dependabot/cli", Directory: "/"},
+		}
+	}
+
+	state, err := loadPRState(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := newJob()
+	state.populate(context.Background(), job)
+	if job.ExistingPullRequests != nil || job.ExistingGroupPullRequests != nil {
+		t.Fatal("expected no existing pull requests on the first run")
+	}
+
+	state.record(job, []model.Output{
+		{Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+			PRTitle:      "Bump a from 1.0.0 to 1.1.0",
+			Dependencies: []model.Dependency{{Name: "a", Version: version("1.1.0")}},
+		}}},
+		{Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+			PRTitle:         "Bump the tools group",
+			Dependencies:    []model.Dependency{{Name: "b", Version: version("2.0.0")}, {Name: "c", Version: version("3.0.0")}},
+			DependencyGroup: map[string]any{"name": "tools"},
+		}}},
+		{Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+			PRTitle:      "Bump d from 1.0.0 to 2.0.0",
+			Dependencies: []model.Dependency{{Name: "d", Version: version("2.0.0")}},
+		}}},
+	}, now)
+	if err = state.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second run sees the pull requests of the first
+	if state, err = loadPRState(filename); err != nil {
+		t.Fatal(err)
+	}
+	job = newJob()
+	state.populate(context.Background(), job)
+	expectedPRs := [][]model.ExistingPR{
+		{{DependencyName: "a", DependencyVersion: "1.1.0"}},
+		{{DependencyName: "d", DependencyVersion: "2.0.0"}},
+	}
+	if !reflect.DeepEqual(job.ExistingPullRequests, expectedPRs) {
+		t.Errorf("unexpected existing pull requests %v", job.ExistingPullRequests)
+	}
+	expectedGroups := []model.ExistingGroupPR{{
+		DependencyGroupName: "tools",
+		Dependencies:        []model.ExistingPR{{DependencyName: "b", DependencyVersion: "2.0.0"}, {DependencyName: "c", DependencyVersion: "3.0.0"}},
+	}}
+	if !reflect.DeepEqual(job.ExistingGroupPullRequests, expectedGroups) {
+		t.Errorf("unexpected existing group pull requests %v", job.ExistingGroupPullRequests)
+	}
+
+	// other package managers don't
+	other := newJob()
+	other.PackageManager = "npm_and_yarn"
+	state.populate(context.Background(), other)
+	if other.ExistingPullRequests != nil {
+		t.Errorf("expected no existing pull requests for another package manager")
+	}
+
+	later := now.Add(time.Hour)
+	state.record(job, []model.Output{
+		{Type: "close_pull_request", Expect: model.UpdateWrapper{Data: model.ClosePullRequest{
+			DependencyNames: []string{"d"}, Reason: "up_to_date",
+		}}},
+		{Type: "update_pull_request", Expect: model.UpdateWrapper{Data: model.UpdatePullRequest{
+			PRTitle: "Bump the tools group with 2 updates", DependencyNames: []string{"b", "c"},
+			DependencyGroup: map[string]any{"name": "tools"},
+		}}},
+		{Type: "create_pull_request", Expect: model.UpdateWrapper{Data: model.CreatePullRequest{
+			PRTitle:      "Bump a from 1.0.0 to 1.2.0",
+			Dependencies: []model.Dependency{{Name: "a", Version: version("1.2.0")}},
+		}}},
+	}, later)
+
+	prs := state.find(job).PullRequests
+	if len(prs) != 2 {
+		t.Fatalf("expected 2 open pull requests, got %d", len(prs))
+	}
+	if prs[0].Group != "tools" || prs[0].Title != "Bump the tools group with 2 updates" || !prs[0].UpdatedAt.Equal(later) {
+		t.Errorf("expected the group pull request to be updated, got %+v", prs[0])
+	}
+	if prs[1].Title != "Bump a from 1.0.0 to 1.2.0" || prs[1].Dependencies[0].DependencyVersion != "1.2.0" {
+		t.Errorf("expected the pull request for a to be replaced, got %+v", prs[1])
+	}
+}
+
+func Test_prState_jobListsExisting(t *testing.T) {
+	state := &prState{Jobs: []*prStateJob{{
+		Provider: "github", Repo: "dependabot/cli", Directory: "/", PackageManager: "go_modules",
+		PullRequests: []*prStatePull{{Dependencies: []model.ExistingPR{{DependencyName: "a"}}}},
+	}}}
+	listed := [][]model.ExistingPR{{{DependencyName: "b", DependencyVersion: "1.0.0"}}}
+	job := &model.Job{
+		PackageManager:       "go_modules",
+		Source:               model.Source{Provider: "github", Repo: "dependabot/cli", Directory: "/"},
+		ExistingPullRequests: listed,
+	}
+	state.populate(context.Background(), job)
+	if !reflect.DeepEqual(job.ExistingPullRequests, listed) {
+		t.Errorf("expected the job's existing pull requests to be kept, got %v", job.ExistingPullRequests)
+	}
+}
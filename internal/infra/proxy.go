@@ -13,7 +13,6 @@ import (
 	"github.com/moby/moby/pkg/namesgenerator"
 	"github.com/moby/moby/pkg/stdcopy"
 	"io"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
@@ -29,6 +28,7 @@ type Proxy struct {
 	containerID string
 	url         string
 	ca          CertificateAuthority
+	logs        io.Writer
 }
 
 func NewProxy(ctx context.Context, cli *client.Client, params *RunParams, nets *Networks) (*Proxy, error) {
@@ -68,6 +68,14 @@ func NewProxy(ctx context.Context, cli *client.Client, params *RunParams, nets *
 		})
 	}
 	hostCfg.ExtraHosts = append(hostCfg.ExtraHosts, params.ExtraHosts...)
+	if params.gitServerCert != "" {
+		hostCfg.Mounts = append(hostCfg.Mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   params.gitServerCert,
+			Target:   gitServerCertPath,
+			ReadOnly: true,
+		})
+	}
 	if params.CacheDir != "" {
 		_ = os.MkdirAll(params.CacheDir, 0744)
 		cacheDir, _ := filepath.Abs(params.CacheDir)
@@ -98,6 +106,7 @@ func NewProxy(ctx context.Context, cli *client.Client, params *RunParams, nets *
 		cli:         cli,
 		containerID: proxyContainer.ID,
 		ca:          ca,
+		logs:        params.logs(),
 	}
 
 	if err = putProxyConfig(ctx, cli, proxyConfig, proxyContainer.ID); err != nil {
@@ -130,7 +139,7 @@ func NewProxy(ctx context.Context, cli *client.Client, params *RunParams, nets *
 		proxy.url = fmt.Sprintf("http://%s:1080", containerInfo.NetworkSettings.Networks[nets.noInternetName].IPAddress)
 	} else {
 		// This should only happen during testing, adding a warning in case
-		log.Println("Warning: no-internet network not found")
+		logf(ctx, "Warning: no-internet network not found\n")
 	}
 
 	return proxy, nil
@@ -163,7 +172,7 @@ func (p *Proxy) TailLogs(ctx context.Context, cli *client.Client) {
 
 	r, w := io.Pipe()
 	go func() {
-		_, _ = io.Copy(os.Stderr, prefixer.New(r, "  proxy | "))
+		_, _ = io.Copy(p.logs, prefixer.New(r, "  proxy | "))
 	}()
 	_, _ = stdcopy.StdCopy(w, w, out)
 }
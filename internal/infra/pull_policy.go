@@ -0,0 +1,95 @@
+package infra
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	// PullAlways pulls images before every run.
+	PullAlways = "always"
+	// PullMissing only pulls images that aren't present locally.
+	PullMissing = "missing"
+	// PullNever never pulls, the images must already be present.
+	PullNever = "never"
+	// PullNewerThan pulls images that are missing or were created longer ago than the policy's duration.
+	PullNewerThan = "newer-than"
+)
+
+// PullPolicy decides when the updater, proxy and collector images are pulled. The zero value is
+// the same as PullMissing.
+type PullPolicy struct {
+	Mode string
+	// NewerThan is the maximum age of a local image when Mode is PullNewerThan.
+	NewerThan time.Duration
+}
+
+// ParsePullPolicy parses always, missing, never, or newer-than=<duration>. For compatibility with the
+// old boolean flag, true is the same as missing and false is the same as never.
+func ParsePullPolicy(s string) (PullPolicy, error) {
+	switch s {
+	case PullAlways, PullMissing, PullNever:
+		return PullPolicy{Mode: s}, nil
+	case "true":
+		return PullPolicy{Mode: PullMissing}, nil
+	case "false":
+		return PullPolicy{Mode: PullNever}, nil
+	}
+	if value, ok := strings.CutPrefix(s, PullNewerThan+"="); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return PullPolicy{}, fmt.Errorf("invalid pull policy %q: newer-than requires a positive duration, e.g. newer-than=24h", s)
+		}
+		return PullPolicy{Mode: PullNewerThan, NewerThan: d}, nil
+	}
+	return PullPolicy{}, fmt.Errorf("invalid pull policy %q: must be always, missing, never, or newer-than=<duration>", s)
+}
+
+func (p *PullPolicy) String() string {
+	switch p.Mode {
+	case "":
+		return PullMissing
+	case PullNewerThan:
+		return fmt.Sprintf("%s=%s", PullNewerThan, p.NewerThan)
+	}
+	return p.Mode
+}
+
+// Set implements pflag.Value so the policy can be used as a flag.
+func (p *PullPolicy) Set(s string) error {
+	policy, err := ParsePullPolicy(s)
+	if err != nil {
+		return err
+	}
+	*p = policy
+	return nil
+}
+
+// Type implements pflag.Value.
+func (p *PullPolicy) Type() string {
+	return "policy"
+}
+
+// shouldPull reports whether an image should be pulled given the local copy, if there is one.
+func (p *PullPolicy) shouldPull(local *types.ImageInspect, now time.Time) bool {
+	switch p.Mode {
+	case PullAlways:
+		return true
+	case PullNever:
+		return false
+	case PullNewerThan:
+		if local == nil {
+			return true
+		}
+		created, err := time.Parse(time.RFC3339Nano, local.Created)
+		if err != nil {
+			return true
+		}
+		return now.Sub(created) > p.NewerThan
+	default:
+		return local == nil
+	}
+}
@@ -0,0 +1,64 @@
+package infra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestParsePullPolicy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected PullPolicy
+		wantErr  bool
+	}{
+		{input: "always", expected: PullPolicy{Mode: PullAlways}},
+		{input: "missing", expected: PullPolicy{Mode: PullMissing}},
+		{input: "never", expected: PullPolicy{Mode: PullNever}},
+		{input: "true", expected: PullPolicy{Mode: PullMissing}},
+		{input: "false", expected: PullPolicy{Mode: PullNever}},
+		{input: "newer-than=24h", expected: PullPolicy{Mode: PullNewerThan, NewerThan: 24 * time.Hour}},
+		{input: "newer-than=yesterday", wantErr: true},
+		{input: "newer-than=-1h", wantErr: true},
+		{input: "sometimes", wantErr: true},
+	}
+	for _, test := range tests {
+		actual, err := ParsePullPolicy(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("for %q unexpected error: %v", test.input, err)
+		}
+		if actual != test.expected {
+			t.Errorf("for %q expected %v got %v", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestPullPolicy_shouldPull(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	fresh := &types.ImageInspect{Created: now.Add(-time.Hour).Format(time.RFC3339Nano)}
+	stale := &types.ImageInspect{Created: now.Add(-72 * time.Hour).Format(time.RFC3339Nano)}
+	newerThanDay := PullPolicy{Mode: PullNewerThan, NewerThan: 24 * time.Hour}
+
+	tests := []struct {
+		name     string
+		policy   PullPolicy
+		local    *types.ImageInspect
+		expected bool
+	}{
+		{"default pulls missing", PullPolicy{}, nil, true},
+		{"default uses local", PullPolicy{}, stale, false},
+		{"always pulls", PullPolicy{Mode: PullAlways}, fresh, true},
+		{"never pulls", PullPolicy{Mode: PullNever}, nil, false},
+		{"newer-than pulls missing", newerThanDay, nil, true},
+		{"newer-than uses fresh", newerThanDay, fresh, false},
+		{"newer-than pulls stale", newerThanDay, stale, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := test.policy.shouldPull(test.local, now); actual != test.expected {
+				t.Errorf("expected %v got %v", test.expected, actual)
+			}
+		})
+	}
+}
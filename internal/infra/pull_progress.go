@@ -0,0 +1,112 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/docker/cli/cli/streams"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-units"
+)
+
+// pullProgressInterval is how often a summary line is logged when stderr isn't a terminal.
+const pullProgressInterval = 10 * time.Second
+
+// displayPullProgress consumes the JSON message stream returned by ImagePull. On a terminal it renders
+// a progress bar per layer like `docker pull` does, otherwise it logs a summary line periodically.
+// Errors embedded in the stream, like a failed layer download, are returned.
+func displayPullProgress(ctx context.Context, in io.Reader, image string) error {
+	out := streams.NewOut(os.Stderr)
+	if out.IsTerminal() {
+		return jsonmessage.DisplayJSONMessagesToStream(in, out, nil)
+	}
+	return logPullProgress(in, image, pullProgressInterval, func(format string, v ...any) {
+		logf(ctx, format, v...)
+	})
+}
+
+type layerProgress struct {
+	current, total int64
+	done           bool
+}
+
+// logPullProgress decodes the pull stream and calls logf with a summary at most once per interval,
+// and once more when the pull completes.
+func logPullProgress(in io.Reader, image string, interval time.Duration, logf func(format string, v ...any)) error {
+	layers := map[string]*layerProgress{}
+	var order []string
+	lastLog := time.Now()
+
+	summary := func() {
+		var complete int
+		var current, total int64
+		for _, id := range order {
+			layer := layers[id]
+			if layer.done {
+				complete++
+			}
+			current += layer.current
+			total += layer.total
+		}
+		logf("pulling image %s: %d/%d layers complete, %s/%s downloaded\n",
+			image, complete, len(order), units.HumanSize(float64(current)), units.HumanSize(float64(total)))
+	}
+
+	decoder := json.NewDecoder(in)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to decode pull progress: %w", err)
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if msg.ErrorMessage != "" {
+			return errors.New(msg.ErrorMessage)
+		}
+		if msg.ID == "" || (msg.Progress == nil && msg.Status == "") {
+			continue
+		}
+
+		layer, ok := layers[msg.ID]
+		if !ok {
+			// the first message of a pull is keyed by the tag, not a layer
+			if msg.Status != "Pulling fs layer" && msg.Status != "Already exists" && msg.Status != "Waiting" {
+				continue
+			}
+			layer = &layerProgress{}
+			layers[msg.ID] = layer
+			order = append(order, msg.ID)
+		}
+		switch msg.Status {
+		case "Downloading":
+			if msg.Progress != nil {
+				layer.current = msg.Progress.Current
+				layer.total = msg.Progress.Total
+			}
+		case "Download complete":
+			layer.current = layer.total
+		case "Pull complete", "Already exists":
+			layer.current = layer.total
+			layer.done = true
+		}
+
+		if time.Since(lastLog) >= interval {
+			summary()
+			lastLog = time.Now()
+		}
+	}
+
+	if len(order) > 0 {
+		summary()
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package infra
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_logPullProgress(t *testing.T) {
+	t.Run("summarizes layers", func(t *testing.T) {
+		stream := strings.NewReader(`
+{"status":"Pulling from dependabot/dependabot-updater-gomod","id":"latest"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"a"}
+{"status":"Already exists","progressDetail":{},"id":"b"}
+{"status":"Downloading","progressDetail":{"current":500,"total":1000},"id":"a"}
+{"status":"Download complete","progressDetail":{},"id":"a"}
+{"status":"Pull complete","progressDetail":{},"id":"a"}
+{"status":"Digest: sha256:abc"}
+{"status":"Status: Downloaded newer image for ghcr.io/dependabot/dependabot-updater-gomod:latest"}
+`)
+		var lines []string
+		logf := func(format string, v ...any) {
+			lines = append(lines, fmt.Sprintf(format, v...))
+		}
+		if err := logPullProgress(stream, "image", 0, logf); err != nil {
+			t.Fatal(err)
+		}
+		last := lines[len(lines)-1]
+		if !strings.Contains(last, "2/2 layers complete, 1kB/1kB downloaded") {
+			t.Errorf("unexpected summary: %q", last)
+		}
+	})
+
+	t.Run("returns errors from the stream", func(t *testing.T) {
+		stream := strings.NewReader(`
+{"status":"Pulling fs layer","progressDetail":{},"id":"a"}
+{"errorDetail":{"message":"unauthorized: authentication required"},"error":"unauthorized: authentication required"}
+`)
+		err := logPullProgress(stream, "image", 0, func(string, ...any) {})
+		if err == nil || err.Error() != "unauthorized: authentication required" {
+			t.Errorf("expected the stream error, got %v", err)
+		}
+	})
+}
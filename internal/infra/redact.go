@@ -0,0 +1,404 @@
+package infra
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Pseudonyms maps the hosts, owner and repository names, and dependency names of scenarios to the
+// names they're replaced with, so the redacted scenarios can be traced back to the originals locally.
+type Pseudonyms struct {
+	Hosts map[string]string `yaml:"hosts,omitempty"`
+	// Names are the segments of the repositories, so repositories of the same owner share its pseudonym
+	Names map[string]string `yaml:"names,omitempty"`
+	// Repos are the repositories, like owner/repo, which are only replaced as a whole since their
+	// segments are often common words like api or cli
+	Repos        map[string]string `yaml:"repos,omitempty"`
+	Dependencies map[string]string `yaml:"dependencies,omitempty"`
+}
+
+// publicHosts aren't redacted since they don't reveal anything and the updater treats them specially.
+var publicHosts = map[string]bool{
+	"github.com":             true,
+	"api.github.com":         true,
+	"ghcr.io":                true,
+	"gitlab.com":             true,
+	"bitbucket.org":          true,
+	"dev.azure.com":          true,
+	"pkgs.dev.azure.com":     true,
+	"registry.npmjs.org":     true,
+	"registry.yarnpkg.com":   true,
+	"rubygems.org":           true,
+	"pypi.org":               true,
+	"files.pythonhosted.org": true,
+	"proxy.golang.org":       true,
+	"repo.maven.apache.org":  true,
+	"api.nuget.org":          true,
+	"index.docker.io":        true,
+	"registry-1.docker.io":   true,
+	"crates.io":              true,
+}
+
+// credentialHostKeys are the credential fields with a host or URL in them.
+var credentialHostKeys = []string{"host", "registry", "url", "index-url"}
+
+// credentialSecretKeys are removed from the credentials metadata in case a scenario was edited by hand, along
+// with usernames which can identify people, like a Bitbucket username.
+var credentialSecretKeys = map[string]bool{"token": true, "password": true, "key": true, "auth-key": true, "username": true}
+
+// unredactedKeys have values that are part of the job API rather than names from the repository.
+var unredactedKeys = map[string]bool{
+	"package-manager":  true,
+	"provider":         true,
+	"type":             true,
+	"content_encoding": true,
+	"operation":        true,
+}
+
+// LoadPseudonyms reads a mapping file written by a previous redaction, which doesn't have to exist yet.
+func LoadPseudonyms(filename string) (*Pseudonyms, error) {
+	pseudonyms := &Pseudonyms{}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pseudonyms, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping file: %w", err)
+	}
+	if err = yaml.Unmarshal(data, pseudonyms); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file %s: %w", filename, err)
+	}
+	return pseudonyms, nil
+}
+
+// Save writes the mapping file, only readable by the user since it holds the names that were redacted.
+func (p *Pseudonyms) Save(filename string) error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mapping: %w", err)
+	}
+	if err = os.WriteFile(filename, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write mapping file: %w", err)
+	}
+	return nil
+}
+
+// RedactScenario removes the credentials from a scenario and replaces its hosts, owner and repository
+// names, the given dependencies, and any extra hosts with pseudonyms, consistently across the input and
+// output. The pseudonyms are added to p, reusing the ones it already has.
+func RedactScenario(data []byte, p *Pseudonyms, dependencies, hosts []string) ([]byte, error) {
+	var scenario model.Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("failed to decode scenario: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode scenario: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, errors.New("the scenario is empty")
+	}
+	if mappingValue(doc.Content[0], "input") == nil && mappingValue(doc.Content[0], "output") == nil {
+		// an input file would otherwise come out unredacted
+		return nil, errors.New("expected a scenario with an input or output")
+	}
+
+	p.collect(&scenario, dependencies, hosts)
+	removeCredentials(doc.Content[0])
+	newRedactor(p).node(doc.Content[0], "")
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scenario: %w", err)
+	}
+	return out, nil
+}
+
+// collect assigns pseudonyms to the names in the scenario that don't have one yet.
+func (p *Pseudonyms) collect(scenario *model.Scenario, dependencies, hosts []string) {
+	if p.Hosts == nil {
+		p.Hosts = map[string]string{}
+	}
+	if p.Names == nil {
+		p.Names = map[string]string{}
+	}
+	if p.Repos == nil {
+		p.Repos = map[string]string{}
+	}
+	if p.Dependencies == nil {
+		p.Dependencies = map[string]string{}
+	}
+
+	source := scenario.Input.Job.Source
+	// CodeCommit has the region as the hostname
+	if source.Hostname != nil && source.Provider != "codecommit" {
+		hosts = append(hosts, *source.Hostname)
+	}
+	if source.APIEndpoint != nil {
+		hosts = append(hosts, *source.APIEndpoint)
+	}
+	for _, creds := range [][]model.Credential{scenario.Input.Credentials, scenario.Input.Job.CredentialsMetadata} {
+		for _, cred := range creds {
+			for _, key := range credentialHostKeys {
+				if value, ok := cred[key].(string); ok {
+					hosts = append(hosts, value)
+				}
+			}
+		}
+	}
+	for _, host := range hosts {
+		if host = hostOf(host); host != "" && !publicHosts[host] {
+			assign(p.Hosts, host, "host-%d.example.com")
+		}
+	}
+
+	repo := strings.Trim(source.Repo, "/")
+	segments := strings.Split(repo, "/")
+	pseudonyms := make([]string, len(segments))
+	for i, segment := range segments {
+		switch {
+		case segment == "" || segment == "_git":
+		case i == len(segments)-1:
+			assign(p.Names, segment, "repo-%d")
+		case i == 0:
+			assign(p.Names, segment, "owner-%d")
+		default:
+			assign(p.Names, segment, "group-%d")
+		}
+		pseudonyms[i] = firstNonEmpty(p.Names[segment], segment)
+	}
+	if repo != "" {
+		p.Repos[repo] = strings.Join(pseudonyms, "/")
+	}
+
+	for _, dependency := range dependencies {
+		assign(p.Dependencies, dependency, "dependency-%d")
+	}
+}
+
+// assign adds a pseudonym for the name, numbered after the others of the same format.
+func assign(pseudonyms map[string]string, name, format string) {
+	if _, ok := pseudonyms[name]; ok || name == "" {
+		return
+	}
+	prefix, _, _ := strings.Cut(format, "%d")
+	n := 1
+	for _, pseudonym := range pseudonyms {
+		if strings.HasPrefix(pseudonym, prefix) {
+			n++
+		}
+	}
+	pseudonyms[name] = fmt.Sprintf(format, n)
+}
+
+// hostOf returns the hostname of a host, a URL, or a registry like npm.example.com/scope.
+func hostOf(value string) string {
+	if strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(u.Hostname())
+	}
+	host, _, _ := strings.Cut(value, "/")
+	return strings.ToLower((&url.URL{Host: host}).Hostname())
+}
+
+// removeCredentials drops the input's credentials and the secrets from the credentials metadata.
+func removeCredentials(root *yaml.Node) {
+	input := mappingValue(root, "input")
+	if input == nil {
+		return
+	}
+	deleteMappingKey(input, "credentials")
+	metadata := mappingValue(mappingValue(input, "job"), "credentials-metadata")
+	if metadata == nil {
+		return
+	}
+	for _, cred := range metadata.Content {
+		for key := range credentialSecretKeys {
+			deleteMappingKey(cred, key)
+		}
+	}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func deleteMappingKey(node *yaml.Node, key string) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// redactor replaces the names in a string with their pseudonyms in a single pass, so a pseudonym is
+// never replaced again.
+type redactor struct {
+	names      []string
+	pseudonyms map[string]string
+}
+
+func newRedactor(p *Pseudonyms) *redactor {
+	r := &redactor{pseudonyms: map[string]string{}}
+	for _, m := range []map[string]string{p.Dependencies, p.Hosts, p.Repos} {
+		for name, pseudonym := range m {
+			if _, ok := r.pseudonyms[name]; !ok {
+				r.pseudonyms[name] = pseudonym
+				r.names = append(r.names, name)
+			}
+		}
+	}
+	// the longest match wins, e.g. a dependency named like a host
+	sort.Slice(r.names, func(i, j int) bool {
+		if len(r.names[i]) != len(r.names[j]) {
+			return len(r.names[i]) > len(r.names[j])
+		}
+		return r.names[i] < r.names[j]
+	})
+	return r
+}
+
+// replace replaces the names that aren't part of a longer name or a public host, so the repository
+// "acme/api" is replaced in "https://github.com/acme/api/pull/1" but not in "acme/api-docs", and a
+// dependency named "github" isn't replaced in "api.github.com".
+func (r *redactor) replace(s string) string {
+	public := publicHostRanges(s)
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		replaced := false
+		if (i == 0 || !isWordChar(s[i-1])) && public[i] == 0 {
+			for _, name := range r.names {
+				end := i + len(name)
+				if strings.HasPrefix(s[i:], name) && (end == len(s) || !isWordChar(s[end])) && public[end-1] == 0 {
+					b.WriteString(r.pseudonyms[name])
+					i = end
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// publicHostRanges marks the bytes of s that are a public host with a non-zero value. Only whole
+// hostnames count, so github.com is public in "https://github.com/acme" but a private host like
+// github.com.acme.internal or acme-gitlab.com is redacted.
+func publicHostRanges(s string) []byte {
+	public := make([]byte, len(s))
+	for host := range publicHosts {
+		for offset := 0; ; {
+			i := strings.Index(s[offset:], host)
+			if i < 0 {
+				break
+			}
+			start, end := offset+i, offset+i+len(host)
+			offset = end
+			if start > 0 && isHostChar(s[start-1]) {
+				continue
+			}
+			// a dot after the host continues it, unless it ends a sentence
+			if end < len(s) && (s[end] != '.' && isHostChar(s[end]) || s[end] == '.' && end+1 < len(s) && isHostChar(s[end+1])) {
+				continue
+			}
+			for j := start; j < end; j++ {
+				public[j] = 1
+			}
+		}
+	}
+	return public
+}
+
+// isHostChar is whether c can be part of a hostname.
+func isHostChar(c byte) bool {
+	return c == '.' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isWordChar is whether c can be part of a name, which includes - so that "lodash" isn't replaced in
+// "lodash-es".
+func isWordChar(c byte) bool {
+	return c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// node redacts the values under the node, key is the mapping key the node is the value of.
+func (r *redactor) node(node *yaml.Node, key string) {
+	r.comments(node)
+	// scenarios written as JSON come out as YAML like the recorded ones, a redacted value that needs
+	// quotes is quoted by the encoder
+	node.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !unredactedKeys[key] && node.Tag != "!!binary" {
+			node.Value = r.replace(node.Value)
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			r.node(child, key)
+		}
+	case yaml.MappingNode:
+		base64Content := false
+		if encoding := mappingValue(node, "content_encoding"); encoding != nil && encoding.Value == "base64" {
+			base64Content = true
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			// the comments above an entry are on its key
+			r.comments(k)
+			k.Style &^= yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+			// the images and timings are the CLI's own
+			if (key == "" && k.Value == "timings") || (key == "input" && k.Value == "images") {
+				continue
+			}
+			if base64Content && k.Value == "content" && v.Kind == yaml.ScalarNode {
+				r.base64(v)
+				continue
+			}
+			r.node(v, k.Value)
+		}
+	}
+}
+
+func (r *redactor) comments(node *yaml.Node) {
+	node.HeadComment = r.replace(node.HeadComment)
+	node.LineComment = r.replace(node.LineComment)
+	node.FootComment = r.replace(node.FootComment)
+}
+
+// base64 redacts the decoded content of base64 encoded files, which are left as is if they aren't text.
+func (r *redactor) base64(node *yaml.Node) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(node.Value), ""))
+	if err != nil || !utf8.Valid(decoded) {
+		return
+	}
+	node.Value = base64.StdEncoding.EncodeToString([]byte(r.replace(string(decoded))))
+}
@@ -0,0 +1,202 @@
+package infra
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRedactScenario(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(`{"dependencies":{"@acme/widgets":"1.1.0"}}`))
+	scenario := `input:
+    job:
+        package-manager: npm_and_yarn
+        source:
+            provider: github
+            repo: acme/payments
+            hostname: ghes.acme.internal
+            api-endpoint: https://ghes.acme.internal/api/v3/
+        credentials-metadata:
+          - type: npm_registry
+            registry: npm.acme.internal/scope
+            token: leaked
+            username: jdoe
+    credentials:
+      - type: npm_registry
+        registry: npm.acme.internal/scope
+        token: secret
+    images:
+        updater: ghcr.io/dependabot/dependabot-updater-npm@sha256:abc
+output:
+  - type: create_pull_request
+    expect:
+        data:
+            dependencies:
+              - name: '@acme/widgets'
+                version: 1.1.0
+            pr-title: Bump @acme/widgets from 1.0.0 to 1.1.0 # see https://ghes.acme.internal/acme/payments
+            # opened for acme/payments
+            pr-body: Resolved from npm.acme.internal, not registry.npmjs.org or acmecorp
+            updated-dependency-files:
+              - content: ` + encoded + `
+                content_encoding: base64
+                name: package.json
+`
+
+	p := &Pseudonyms{}
+	out, err := RedactScenario([]byte(scenario), p, []string{"@acme/widgets"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Pseudonyms{
+		Hosts:        map[string]string{"ghes.acme.internal": "host-1.example.com", "npm.acme.internal": "host-2.example.com"},
+		Names:        map[string]string{"acme": "owner-1", "payments": "repo-1"},
+		Repos:        map[string]string{"acme/payments": "owner-1/repo-1"},
+		Dependencies: map[string]string{"@acme/widgets": "dependency-1"},
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("expected pseudonyms %+v, got %+v", expected, p)
+	}
+
+	redacted := string(out)
+	for _, leaked := range []string{"acme/", "acme ", "jdoe", "internal", "secret", "leaked", "payments"} {
+		if strings.Contains(redacted, leaked) {
+			t.Errorf("expected %q to be redacted:\n%s", leaked, redacted)
+		}
+	}
+	for _, kept := range []string{
+		"package-manager: npm_and_yarn",
+		"repo: owner-1/repo-1",
+		"hostname: host-1.example.com",
+		"registry: host-2.example.com/scope",
+		"ghcr.io/dependabot/dependabot-updater-npm@sha256:abc",
+		"Bump dependency-1 from 1.0.0 to 1.1.0 # see https://host-1.example.com/owner-1/repo-1",
+		"not registry.npmjs.org or acmecorp",
+	} {
+		if !strings.Contains(redacted, kept) {
+			t.Errorf("expected %q in:\n%s", kept, redacted)
+		}
+	}
+
+	var actual model.Scenario
+	if err = yaml.Unmarshal(out, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if len(actual.Input.Credentials) != 0 {
+		t.Errorf("expected the credentials to be removed, got %v", actual.Input.Credentials)
+	}
+	files := actual.Output[0].Expect.Data.(map[string]any)["updated-dependency-files"].([]any)
+	content, _ := base64.StdEncoding.DecodeString(files[0].(map[string]any)["content"].(string))
+	if string(content) != `{"dependencies":{"dependency-1":"1.1.0"}}` {
+		t.Errorf("unexpected base64 content %s", content)
+	}
+}
+
+// Repositories are often named like other things, so they're only replaced as owner/repo.
+func TestRedactScenario_commonRepoName(t *testing.T) {
+	scenario := `input:
+    job:
+        package-manager: npm_and_yarn
+        source:
+            provider: github
+            repo: acme/api
+            directory: /api
+output:
+  - type: create_pull_request
+    expect:
+        data:
+            pr-body: See https://github.com/acme/api/pull/1, the api and acme/api-docs, and api.github.com
+            updated-dependency-files:
+              - content: '{"name": "api", "dependencies": {"lodash": "1.0.0", "lodash-es": "1.0.0"}}'
+                content_encoding: utf-8
+                name: package.json
+`
+	out, err := RedactScenario([]byte(scenario), &Pseudonyms{}, []string{"lodash", "github"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redacted := string(out)
+	for _, expected := range []string{
+		"repo: owner-1/repo-1",
+		"directory: /api",
+		"See https://github.com/owner-1/repo-1/pull/1, the api and acme/api-docs, and api.github.com",
+		`'{"name": "api", "dependencies": {"dependency-1": "1.0.0", "lodash-es": "1.0.0"}}'`,
+	} {
+		if !strings.Contains(redacted, expected) {
+			t.Errorf("expected %q in:\n%s", expected, redacted)
+		}
+	}
+}
+
+// Private hosts named like public ones are redacted, the public hosts only when they're the whole hostname.
+func TestRedactScenario_privateHostsLikePublicOnes(t *testing.T) {
+	scenario := `input:
+    job:
+        package-manager: pip
+        source:
+            provider: github
+            repo: acme/api
+output:
+  - type: create_pull_request
+    expect:
+        data:
+            pr-body: From https://pypi.org.mirror.corp/simple, acme-gitlab.com, github.com.acme.internal, and pypi.org.
+`
+	out, err := RedactScenario([]byte(scenario), &Pseudonyms{}, nil, []string{"pypi.org.mirror.corp", "acme-gitlab.com", "github.com.acme.internal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redacted := string(out)
+	for _, host := range []string{"pypi.org.mirror.corp", "acme-gitlab.com", "github.com.acme.internal"} {
+		if strings.Contains(redacted, host) {
+			t.Errorf("expected %s to be redacted in:\n%s", host, redacted)
+		}
+	}
+	if !strings.Contains(redacted, "and pypi.org.") {
+		t.Errorf("expected the public host to be kept in:\n%s", redacted)
+	}
+}
+
+func TestRedactScenario_reusesPseudonyms(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "mapping.yml")
+	p, err := LoadPseudonyms(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = RedactScenario([]byte("input:\n    job:\n        source:\n            repo: acme/payments\n"), p, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	// a JSON scenario of another repository of the same owner
+	p, err = LoadPseudonyms(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := RedactScenario([]byte(`{"input":{"job":{"source":{"repo":"acme/ledger"}}}}`), p, nil, []string{"git.acme.internal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "repo: owner-1/repo-2") {
+		t.Errorf("expected the owner's pseudonym to be reused, got:\n%s", out)
+	}
+	if p.Hosts["git.acme.internal"] != "host-1.example.com" {
+		t.Errorf("expected the extra host to get a pseudonym, got %v", p.Hosts)
+	}
+}
+
+func TestRedactScenario_notAScenario(t *testing.T) {
+	_, err := RedactScenario([]byte("job:\n    source:\n        repo: acme/payments\n"), &Pseudonyms{}, nil, nil)
+	if err == nil {
+		t.Error("expected an error for an input file")
+	}
+}
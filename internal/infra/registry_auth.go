@@ -0,0 +1,99 @@
+package infra
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/cli/cli/config"
+	"github.com/moby/moby/api/types/registry"
+)
+
+// dockerHubAuthKey is the key Docker uses for Docker Hub in its config file and credential stores.
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// registryAuth returns the encoded credentials used to pull the image, or an empty string to pull
+// anonymously. LOCAL_GITHUB_ACCESS_TOKEN (ghcr.io) and AZURE_REGISTRY_USERNAME/PASSWORD (*.azurecr.io)
+// take precedence, otherwise credentials are resolved from the Docker config file the same way
+// `docker pull` does, including credHelpers and credsStore.
+func registryAuth(ctx context.Context, image string) (string, error) {
+	host, err := registryHost(image)
+	if err != nil {
+		return "", err
+	}
+
+	if host == "ghcr.io" {
+		if token := os.Getenv("LOCAL_GITHUB_ACCESS_TOKEN"); token != "" {
+			auth := base64.StdEncoding.EncodeToString([]byte("x:" + token))
+			return fmt.Sprintf("Basic %s", auth), nil
+		}
+	}
+
+	if isAzureRegistry(host) {
+		username := os.Getenv("AZURE_REGISTRY_USERNAME")
+		password := os.Getenv("AZURE_REGISTRY_PASSWORD")
+		if username != "" && password != "" {
+			return registry.EncodeAuthConfig(registry.AuthConfig{
+				Username:      username,
+				Password:      password,
+				ServerAddress: host,
+			})
+		}
+	}
+
+	authConfig, err := dockerConfigAuth(host)
+	if err != nil {
+		return "", err
+	}
+	if authConfig == nil {
+		logf(ctx, "Failed to find credentials for pulling image: %s\n", image)
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(*authConfig)
+}
+
+// dockerConfigAuth looks up the credentials for the registry host in the Docker config file,
+// returning nil if there aren't any.
+func dockerConfigAuth(host string) (*registry.AuthConfig, error) {
+	configFile, err := config.Load(config.Dir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Docker config: %w", err)
+	}
+
+	key := host
+	if host == "docker.io" {
+		key = dockerHubAuthKey
+	}
+	authConfig, err := configFile.GetAuthConfig(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials for %s from Docker config: %w", host, err)
+	}
+	if authConfig.Username == "" && authConfig.Password == "" && authConfig.IdentityToken == "" && authConfig.RegistryToken == "" {
+		return nil, nil
+	}
+
+	return &registry.AuthConfig{
+		Username:      authConfig.Username,
+		Password:      authConfig.Password,
+		Auth:          authConfig.Auth,
+		ServerAddress: firstNonEmpty(authConfig.ServerAddress, key),
+		IdentityToken: authConfig.IdentityToken,
+		RegistryToken: authConfig.RegistryToken,
+	}, nil
+}
+
+// registryHost returns the registry hostname of an image reference, e.g. docker.io for "ubuntu".
+func registryHost(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image %v: %w", image, err)
+	}
+	return reference.Domain(named), nil
+}
+
+func isAzureRegistry(host string) bool {
+	return strings.HasSuffix(host, ".azurecr.io")
+}
@@ -0,0 +1,72 @@
+package infra
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/moby/moby/api/types/registry"
+)
+
+func Test_registryAuth(t *testing.T) {
+	dir := t.TempDir()
+	originalDir := config.Dir()
+	config.SetDir(dir)
+	t.Cleanup(func() { config.SetDir(originalDir) })
+
+	auth := base64.StdEncoding.EncodeToString([]byte("harbor-user:harbor-pass"))
+	configJSON := `{"auths":{"harbor.example.com":{"auth":"` + auth + `"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(configJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	decode := func(t *testing.T, encoded string) registry.AuthConfig {
+		data, err := base64.URLEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var authConfig registry.AuthConfig
+		if err = json.Unmarshal(data, &authConfig); err != nil {
+			t.Fatal(err)
+		}
+		return authConfig
+	}
+
+	t.Run("uses the Docker config", func(t *testing.T) {
+		encoded, err := registryAuth(context.Background(), "harbor.example.com/dependabot/dependabot-updater-gomod:latest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		authConfig := decode(t, encoded)
+		if authConfig.Username != "harbor-user" || authConfig.Password != "harbor-pass" {
+			t.Errorf("unexpected credentials: %+v", authConfig)
+		}
+	})
+
+	t.Run("pulls anonymously without credentials", func(t *testing.T) {
+		encoded, err := registryAuth(context.Background(), "ubuntu:22.04")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded != "" {
+			t.Errorf("expected no credentials, got %v", encoded)
+		}
+	})
+
+	t.Run("environment variables take precedence", func(t *testing.T) {
+		t.Setenv("AZURE_REGISTRY_USERNAME", "azure-user")
+		t.Setenv("AZURE_REGISTRY_PASSWORD", "azure-pass")
+		encoded, err := registryAuth(context.Background(), "example.azurecr.io/dependabot-updater-gomod")
+		if err != nil {
+			t.Fatal(err)
+		}
+		authConfig := decode(t, encoded)
+		if authConfig.Username != "azure-user" || authConfig.ServerAddress != "example.azurecr.io" {
+			t.Errorf("unexpected credentials: %+v", authConfig)
+		}
+	})
+}
@@ -2,12 +2,13 @@ package infra
 
 import (
 	"context"
-	"encoding/base64"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"regexp"
@@ -17,13 +18,14 @@ import (
 
 	"github.com/dependabot/cli/internal/model"
 	"github.com/dependabot/cli/internal/server"
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/hexops/gotextdiff"
 	"github.com/hexops/gotextdiff/myers"
 	"github.com/hexops/gotextdiff/span"
-	"github.com/moby/moby/api/types/registry"
 	"github.com/moby/moby/client"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/yaml.v3"
 )
 
@@ -36,16 +38,33 @@ type RunParams struct {
 	Expected []model.Output
 	// directory to copy into the updater container as the repo
 	LocalDir string
+	// Apply writes the changes of the proposed pull requests back into LocalDir
+	Apply bool
+	// ApplyIndex selects the pull request to apply, starting at 1. Zero applies all of them.
+	ApplyIndex int
+	// PatchesDir is where a patch is written for each proposed pull request
+	PatchesDir string
+	// Branches creates a branch in the LocalDir git repository for each proposed pull request
+	Branches bool
+	// StateFile records the pull requests of each run, and tells later runs which are open
+	StateFile string
+	// LocalExclude are .gitignore style patterns of files in LocalDir not to copy
+	LocalExclude []string
+	// PreserveGit copies the git history of LocalDir instead of creating a new repository
+	PreserveGit bool
 	// credentials passed to the proxy
 	Creds []model.Credential
+	// CredentialEnv are the environment variables the credentials may reference, for jobs submitted by
+	// others. Any variable can be referenced when it's nil.
+	CredentialEnv []string
 	// local directory used for caching
 	CacheDir string
 	// write output to a file
 	Output string
 	// ProxyCertPath is the path to a cert for the proxy to trust
 	ProxyCertPath string
-	// attempt to pull images if they aren't local?
-	PullImages bool
+	// PullPolicy decides when the images are pulled
+	PullPolicy PullPolicy
 	// run an interactive shell?
 	Debug bool
 	// Volumes are used to mount directories in Docker
@@ -63,11 +82,35 @@ type RunParams struct {
 	CollectorImage string
 	// CollectorConfigPath is the path to the OpenTelemetry collector configuration file
 	CollectorConfigPath string
+	// ExpectedImages are the images a scenario was recorded with, a warning is logged if they differ
+	ExpectedImages *model.Images
 	// Writer is where API calls will be written to
-	Writer    io.Writer
+	Writer io.Writer
+	// NotifyURLs are sent a JSON summary at the end of the run
+	NotifyURLs []string
+	// NotifySecret signs the notifications with HMAC-SHA256 when set
+	NotifySecret string
+	// LogWriter is where the output of the containers is written to, os.Stderr when nil
+	LogWriter io.Writer
 	InputName string
 	InputRaw  []byte
 	ApiUrl    string
+	// GitRepo is a bare repository served over git smart HTTP as the job's repository
+	GitRepo string
+	// gitServerCert is the CA of the git server, for the proxy to trust
+	gitServerCert string
+	// StrictSpec fails the run when a call doesn't match the API spec, instead of logging a warning
+	StrictSpec bool
+	// ForwardURL is a job API the calls are forwarded to, with the token from $DEPENDABOT_JOB_TOKEN
+	ForwardURL string
+}
+
+// logs returns where the output of the containers is written to.
+func (p *RunParams) logs() io.Writer {
+	if p.LogWriter != nil {
+		return p.LogWriter
+	}
+	return os.Stderr
 }
 
 var gitShaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
@@ -79,10 +122,25 @@ func (p *RunParams) Validate() error {
 	if p.Job.Source.Commit != "" && !gitShaRegex.MatchString(p.Job.Source.Commit) {
 		return fmt.Errorf("commit must be a SHA, or not provided")
 	}
+	if p.Apply && p.LocalDir == "" {
+		return fmt.Errorf("applying pull requests requires a local directory")
+	}
+	if p.Branches && p.LocalDir == "" {
+		return fmt.Errorf("creating branches requires a local directory")
+	}
+	if p.PreserveGit && p.LocalDir == "" {
+		return fmt.Errorf("preserving git history requires a local directory")
+	}
+	if p.GitRepo != "" && p.LocalDir != "" {
+		return fmt.Errorf("serving a git repository can't be used with a local directory")
+	}
+	if p.ForwardURL != "" && p.ApiUrl != "" {
+		return fmt.Errorf("forwarding to a job API can't be used with an API URL")
+	}
 	return nil
 }
 
-func Run(params RunParams) error {
+func Run(params RunParams) (err error) {
 	if err := params.Validate(); err != nil {
 		return err
 	}
@@ -94,19 +152,55 @@ func Run(params RunParams) error {
 	} else {
 		ctx, cancel = context.WithCancel(context.Background())
 	}
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-signals
-		cancel()
+	defer cancel()
+	// stopped at the end of the run, since serve and schedule call Run for every job
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if params.LogWriter != nil {
+		ctx = withLogger(ctx, params.LogWriter)
+	}
+
+	if err := initTracing(ctx); err != nil {
+		return err
+	}
+	ctx, span := startSpan(ctx, "dependabot.run",
+		attribute.String("dependabot.package_manager", params.Job.PackageManager),
+		attribute.String("dependabot.repo", params.Job.Source.Repo),
+	)
+	defer func() {
+		endSpan(span, err)
+		flushTracing(context.Background())
 	}()
 
+	var state *prState
+	if params.StateFile != "" {
+		if state, err = loadPRState(params.StateFile); err != nil {
+			return err
+		}
+		state.populate(ctx, params.Job)
+	}
+
 	api := server.NewAPI(params.Expected, params.Writer)
 	defer api.Stop()
+	api.StrictSpec = params.StrictSpec
+	if params.ForwardURL != "" {
+		if err := api.ForwardTo(params.ForwardURL, os.Getenv("DEPENDABOT_JOB_TOKEN")); err != nil {
+			return err
+		}
+		logf(ctx, "Forwarding API calls to %s\n", params.ForwardURL)
+	}
+
+	started := time.Now()
+	run := &runState{timer: newPhaseTimer()}
+	if len(params.NotifyURLs) > 0 {
+		defer func() {
+			summary := newRunSummary(params.Job, api.Actual.Output, started, run.exitCode, err)
+			notify(context.WithoutCancel(ctx), params.NotifyURLs, params.NotifySecret, summary)
+		}()
+	}
 
 	var outFile *os.File
 	if params.Output != "" {
-		var err error
 		// Open a file for writing but don't truncate it yet since an error will delete the test.
 		// This is done before the test so if the dir isn't writable it doesn't waste time.
 		outFile, err = os.OpenFile(params.Output, os.O_RDWR|os.O_CREATE, 0666)
@@ -117,7 +211,7 @@ func Run(params RunParams) error {
 	}
 
 	expandEnvironmentVariables(api, &params)
-	if err := checkCredAccess(ctx, params.Job, params.Creds); err != nil {
+	if err := checkCredAccess(ctx, params.Job, params.Creds, params.ProxyCertPath); err != nil {
 		return err
 	}
 
@@ -128,17 +222,67 @@ func Run(params RunParams) error {
 	if params.ApiUrl == "" {
 		params.ApiUrl = fmt.Sprintf("http://host.docker.internal:%v", api.Port())
 	}
-	if err := runContainers(ctx, params); err != nil {
+
+	defer func() {
+		run.timer.Print(params.logs())
+		writeTimings(params.Writer, run.timer)
+	}()
+	// the job is only changed for the containers, so the output keeps the original source
+	containerParams := params
+	if params.GitRepo != "" {
+		git, err := newGitServer(ctx, params.GitRepo)
+		if err != nil {
+			return err
+		}
+		defer git.Close()
+		logf(ctx, "Serving %s as https://%s/%s\n", params.GitRepo, git.host(), params.Job.Source.Repo)
+		containerParams = git.route(params)
+	}
+
+	if err := runContainers(ctx, containerParams, run); err != nil {
 		return err
 	}
 
 	api.Complete()
+	api.Actual.Timings = run.timer.Timings()
+	api.Actual.Input.Images = &run.images
+	warnImageMismatch(ctx, params.ExpectedImages, &run.images)
 
 	output, err := generateOutput(params, api, outFile)
 	if err != nil {
 		return err
 	}
 
+	if state != nil {
+		state.record(params.Job, api.Actual.Output, time.Now())
+		if err := state.save(); err != nil {
+			return err
+		}
+	}
+
+	if params.PatchesDir != "" {
+		originals := newOriginalFiles(run.fetched, params.LocalDir)
+		if err := writePatches(ctx, params.PatchesDir, api.Actual.Output, api.OriginalFile, originals); err != nil {
+			return err
+		}
+	}
+
+	if params.Branches {
+		if err := createBranches(ctx, params.LocalDir, params.Job.PackageManager, api.Actual.Output, api.OriginalFile); err != nil {
+			return err
+		}
+	}
+
+	if params.Apply {
+		if err := applyPullRequests(ctx, params.LocalDir, params.ApplyIndex, api.Actual.Output, api.OriginalFile); err != nil {
+			return err
+		}
+	}
+
+	if len(api.UpstreamErrors) > 0 {
+		logf(ctx, "The job API at %s rejected or didn't receive %d calls, the first: %v\n", params.ForwardURL, len(api.UpstreamErrors), api.UpstreamErrors[0])
+	}
+
 	if len(api.Errors) > 0 {
 		return diff(params, outFile, output)
 	}
@@ -146,6 +290,17 @@ func Run(params RunParams) error {
 	return nil
 }
 
+// writeTimings writes the phase timings to the machine-readable output alongside the API calls.
+func writeTimings(w io.Writer, timer *phaseTimer) {
+	if w == nil {
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"type": "phase_timings",
+		"data": timer.Timings(),
+	})
+}
+
 func generateOutput(params RunParams, api *server.API, outFile *os.File) ([]byte, error) {
 	if params.Job.Source.Commit == "" {
 		// store the SHA we worked with for reproducible tests
@@ -205,7 +360,14 @@ var (
 // Some package managers can execute arbitrary code during an update. The credentials are not accessible to the updater,
 // but the proxy injects them in requests, and the updater could execute arbitrary requests. So to be safe, disallow
 // write access on these tokens.
-func checkCredAccess(ctx context.Context, job *model.Job, creds []model.Credential) error {
+func checkCredAccess(ctx context.Context, job *model.Job, creds []model.Credential, caCertPath string) error {
+	client := http.DefaultClient
+	if caCertPath != "" {
+		var err error
+		if client, err = httpClientTrusting(caCertPath); err != nil {
+			return err
+		}
+	}
 	for _, cred := range creds {
 		var credential string
 		if password, ok := cred["password"]; ok && password != "" {
@@ -217,20 +379,17 @@ func checkCredAccess(ctx context.Context, job *model.Job, creds []model.Credenti
 		if !strings.HasPrefix(credential, "ghp_") {
 			continue
 		}
-		apiEndpoint := defaultApiEndpoint
-		if job != nil && job.Source.APIEndpoint != nil && *job.Source.APIEndpoint != "" {
-			apiEndpoint = *job.Source.APIEndpoint
-		}
-		r, err := http.NewRequestWithContext(ctx, "GET", apiEndpoint, http.NoBody)
+		r, err := http.NewRequestWithContext(ctx, "GET", credAPIEndpoint(job, cred), http.NoBody)
 		if err != nil {
 			return fmt.Errorf("failed creating request: %w", err)
 		}
 		r.Header.Set("Authorization", fmt.Sprintf("token %s", credential))
 		r.Header.Set("User-Agent", "dependabot-cli")
-		resp, err := http.DefaultClient.Do(r)
+		resp, err := client.Do(r)
 		if err != nil {
 			return fmt.Errorf("failed making request: %w", err)
 		}
+		_ = resp.Body.Close()
 		if resp.StatusCode != http.StatusOK {
 			return fmt.Errorf("failed request to GitHub API to check access: %s", resp.Status)
 		}
@@ -242,6 +401,38 @@ func checkCredAccess(ctx context.Context, job *model.Job, creds []model.Credenti
 	return nil
 }
 
+// credAPIEndpoint is the API a token is checked against: the job's, like a GitHub Enterprise Server's,
+// unless the credential is for another host.
+func credAPIEndpoint(job *model.Job, cred model.Credential) string {
+	if job == nil || job.Source.APIEndpoint == nil || *job.Source.APIEndpoint == "" {
+		return defaultApiEndpoint
+	}
+	host, _ := cred["host"].(string)
+	if host != "" && job.Source.Hostname != nil && host != (&url.URL{Host: *job.Source.Hostname}).Hostname() {
+		return defaultApiEndpoint
+	}
+	return *job.Source.APIEndpoint
+}
+
+// httpClientTrusting returns a client that trusts the certificates in the file as well as the system's,
+// for servers with a custom CA.
+func httpClientTrusting(certPath string) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", certPath)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &http.Client{Transport: transport}, nil
+}
+
 var packageManagerLookup = map[string]string{
 	"bundler":        "bundler",
 	"cargo":          "cargo",
@@ -295,10 +486,24 @@ func expandEnvironmentVariables(api *server.API, params *RunParams) {
 	}
 
 	// Add the actual credentials from the environment.
+	getenv := os.Getenv
+	if params.CredentialEnv != nil {
+		allowed := map[string]bool{}
+		for _, name := range params.CredentialEnv {
+			allowed[name] = true
+		}
+		getenv = func(name string) string {
+			if !allowed[name] {
+				// left as it is, so a job can't send other secrets to a host of its choosing
+				return "$" + name
+			}
+			return os.Getenv(name)
+		}
+	}
 	for _, cred := range params.Creds {
 		for key, value := range cred {
 			if valueString, ok := value.(string); ok {
-				cred[key] = os.ExpandEnv(valueString)
+				cred[key] = os.Expand(valueString, getenv)
 			}
 		}
 	}
@@ -329,44 +534,70 @@ func generateIgnoreConditions(params *RunParams, actual *model.Scenario) error {
 	return nil
 }
 
-func runContainers(ctx context.Context, params RunParams) (err error) {
+// runState is what runContainers learns during a run that Run needs afterwards.
+type runState struct {
+	timer  *phaseTimer
+	images model.Images
+	// fetched are the files from the fetch_files step, only read when writing patches
+	fetched []model.DependencyFile
+	// exitCode is the updater's exit code, nil if it didn't run
+	exitCode *int
+}
+
+func runContainers(ctx context.Context, params RunParams, run *runState) (err error) {
+	timer := run.timer
+
 	var cli *client.Client
 	cli, err = client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return fmt.Errorf("failed to create Docker client: %w", err)
 	}
 
-	if params.PullImages {
-		err = pullImage(ctx, cli, params.ProxyImage)
-		if err != nil {
-			return err
-		}
-
-		if params.CollectorConfigPath != "" {
-			err = pullImage(ctx, cli, params.CollectorImage)
-			if err != nil {
-				return err
-			}
-		}
+	proxyImage, err := resolveImage(ctx, cli, timer, params.PullPolicy, "proxy", params.ProxyImage)
+	if err != nil {
+		return err
+	}
+	run.images.Proxy = imageDigest(params.ProxyImage, proxyImage)
 
-		err = pullImage(ctx, cli, params.UpdaterImage)
+	if params.CollectorConfigPath != "" {
+		collectorImage, err := resolveImage(ctx, cli, timer, params.PullPolicy, "collector", params.CollectorImage)
 		if err != nil {
 			return err
 		}
+		run.images.Collector = imageDigest(params.CollectorImage, collectorImage)
 	}
 
-	networks, err := NewNetworks(ctx, cli)
+	updaterImage, err := resolveImage(ctx, cli, timer, params.PullPolicy, "updater", params.UpdaterImage)
+	if err != nil {
+		return err
+	}
+	run.images.Updater = imageDigest(params.UpdaterImage, updaterImage)
+	if updaterImage.Config != nil {
+		warnUpdaterCompatibility(ctx, updaterImage.Config.Labels)
+	}
+
+	phaseCtx, done := timer.start(ctx, "create networks")
+	networks, err := NewNetworks(phaseCtx, cli)
+	done(err)
 	if err != nil {
 		return fmt.Errorf("failed to create networks: %w", err)
 	}
-	defer networks.Close()
+	defer func() {
+		_, done := timer.start(ctx, "teardown", attribute.String("dependabot.teardown", "networks"))
+		done(networks.Close())
+	}()
 
-	prox, err := NewProxy(ctx, cli, &params, networks)
+	phaseCtx, done = timer.start(ctx, "start proxy", attribute.String("container.image.name", params.ProxyImage))
+	prox, err := NewProxy(phaseCtx, cli, &params, networks)
+	done(err)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if proxyErr := prox.Close(); proxyErr != nil {
+		_, done := timer.start(ctx, "teardown", attribute.String("dependabot.teardown", "proxy"))
+		proxyErr := prox.Close()
+		done(proxyErr)
+		if proxyErr != nil {
 			err = proxyErr
 		}
 	}()
@@ -378,26 +609,39 @@ func runContainers(ctx context.Context, params RunParams) (err error) {
 
 	var collector *Collector
 	if params.CollectorConfigPath != "" {
-		collector, err = NewCollector(ctx, cli, networks, &params, prox)
+		phaseCtx, done = timer.start(ctx, "start collector", attribute.String("container.image.name", params.CollectorImage))
+		collector, err = NewCollector(phaseCtx, cli, networks, &params, prox)
+		done(err)
 		if err != nil {
 			return err
 		}
-		defer collector.Close()
+		defer func() {
+			_, done := timer.start(ctx, "teardown", attribute.String("dependabot.teardown", "collector"))
+			done(collector.Close())
+		}()
 	}
 
-	updater, err := NewUpdater(ctx, cli, networks, &params, prox, collector)
+	phaseCtx, done = timer.start(ctx, "start updater", attribute.String("container.image.name", params.UpdaterImage))
+	updater, err := NewUpdater(phaseCtx, cli, networks, &params, prox, collector)
+	done(err)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if updaterErr := updater.Close(); updaterErr != nil {
+		_, done := timer.start(ctx, "teardown", attribute.String("dependabot.teardown", "updater"))
+		updaterErr := updater.Close()
+		done(updaterErr)
+		if updaterErr != nil {
 			err = updaterErr
 		}
 	}()
 
 	// put the clone dir in the updater container to be used by during the update
 	if params.LocalDir != "" {
-		if err = putCloneDir(ctx, cli, updater, params.LocalDir); err != nil {
+		phaseCtx, done = timer.start(ctx, "copy clone dir")
+		err = putCloneDir(phaseCtx, cli, updater, params.LocalDir, params.PreserveGit, params.LocalExclude)
+		done(err)
+		if err != nil {
 			return err
 		}
 	}
@@ -407,10 +651,31 @@ func runContainers(ctx context.Context, params RunParams) (err error) {
 			return err
 		}
 	} else {
-		const cmd = "update-ca-certificates && bin/run fetch_files && bin/run update_files"
-		if err := updater.RunCmd(ctx, cmd, dependabot, userEnv(prox.url, params.ApiUrl)...); err != nil {
-			return err
+		// fetch_files and update_files are run separately so each can be timed
+		steps := []struct{ phase, cmd string }{
+			{"fetch_files", "update-ca-certificates && bin/run fetch_files"},
+			{"update_files", "bin/run update_files"},
+		}
+		for _, step := range steps {
+			phaseCtx, done = timer.start(ctx, step.phase)
+			env := append(userEnv(prox.url, params.ApiUrl), traceEnv(phaseCtx)...)
+			err = updater.RunCmd(phaseCtx, step.cmd, dependabot, env...)
+			done(err)
+			if err != nil {
+				return err
+			}
+			if *updater.ExitCode != 0 {
+				break
+			}
+			if step.phase == "fetch_files" && params.PatchesDir != "" {
+				fetched, fetchErr := updater.FetchedFiles(ctx)
+				if fetchErr != nil {
+					logf(ctx, "Failed to read the fetched files, patches will use the --local directory: %v\n", fetchErr)
+				}
+				run.fetched = fetched
+			}
 		}
+		run.exitCode = updater.ExitCode
 		// If the exit code is non-zero, error when using the `update` subcommand, but not the `test` subcommand.
 		if params.Expected == nil && *updater.ExitCode != 0 {
 			return fmt.Errorf("updater exited with code %d", *updater.ExitCode)
@@ -420,15 +685,81 @@ func runContainers(ctx context.Context, params RunParams) (err error) {
 	return nil
 }
 
-func putCloneDir(ctx context.Context, cli *client.Client, updater *Updater, dir string) error {
+// resolveImage pulls the image according to the policy and returns the local image.
+func resolveImage(ctx context.Context, cli *client.Client, timer *phaseTimer, policy PullPolicy, role, image string) (types.ImageInspect, error) {
+	ctx, done := timer.start(ctx, fmt.Sprintf("pull %s image", role), attribute.String("container.image.name", image))
+	inspect, err := pullImage(ctx, cli, image, policy)
+	done(err)
+	return inspect, err
+}
+
+// imageDigest returns the image as a name@digest reference, or the image ID for images that have
+// never been pushed to or pulled from a registry.
+func imageDigest(image string, inspect types.ImageInspect) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return inspect.ID
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return image
+	}
+	for _, repoDigest := range inspect.RepoDigests {
+		digested, err := reference.ParseNormalizedNamed(repoDigest)
+		if err == nil && digested.Name() == named.Name() {
+			return repoDigest
+		}
+	}
+	return inspect.ID
+}
+
+// warnImageMismatch logs when a scenario is run with different images than it was recorded with.
+func warnImageMismatch(ctx context.Context, expected, actual *model.Images) {
+	if expected == nil {
+		return
+	}
+	check := func(role, expected, actual string) {
+		if expected != "" && actual != "" && expected != actual {
+			logf(ctx, "Warning: the %s image %s doesn't match the recorded %s, use --pin-images to run with the recorded images\n", role, actual, expected)
+		}
+	}
+	check("updater", expected.Updater, actual.Updater)
+	check("proxy", expected.Proxy, actual.Proxy)
+	check("collector", expected.Collector, actual.Collector)
+}
+
+func putCloneDir(ctx context.Context, cli *client.Client, updater *Updater, dir string, preserveGit bool, excludes []string) error {
+	source, err := scanLocalSource(dir, excludes)
+	if err != nil {
+		return err
+	}
+	warnLocalSize(ctx, dir, source)
+
+	var local *localGit
+	tarOptions := &archive.TarOptions{ExcludePatterns: source.Excludes}
+	if preserveGit {
+		tmp, err := os.MkdirTemp("", "dependabot-local")
+		if err != nil {
+			return fmt.Errorf("failed to create bundle dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+
+		if local, err = inspectLocalGit(ctx, dir, tmp); err != nil {
+			return err
+		}
+		if local.Bundle != "" {
+			// .git points elsewhere on the host, the bundle replaces it
+			tarOptions.ExcludePatterns = append(tarOptions.ExcludePatterns, ".git")
+		}
+	}
+
 	// Docker won't create the directory, so we have to do it first.
 	const cmd = "mkdir -p " + guestRepoDir
-	err := updater.RunCmd(ctx, cmd, dependabot)
+	err = updater.RunCmd(ctx, cmd, dependabot)
 	if err != nil {
 		return fmt.Errorf("failed to create clone dir: %w", err)
 	}
 
-	r, err := archive.TarWithOptions(dir, &archive.TarOptions{})
+	r, err := archive.TarWithOptions(dir, tarOptions)
 	if err != nil {
 		return fmt.Errorf("failed to tar clone dir: %w", err)
 	}
@@ -439,11 +770,37 @@ func putCloneDir(ctx context.Context, cli *client.Client, updater *Updater, dir
 		return fmt.Errorf("failed to copy clone dir to container: %w", err)
 	}
 
+	if local != nil && local.Bundle != "" {
+		t, err := tarballFile(guestBundlePath, local.Bundle)
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		err = cli.CopyToContainer(ctx, updater.containerID, "/", t, opt)
+		t.Close()
+		if err != nil {
+			return fmt.Errorf("failed to copy bundle to container: %w", err)
+		}
+	}
+
 	err = updater.RunCmd(ctx, "chown -R dependabot "+guestRepoDir, root)
 	if err != nil {
 		return fmt.Errorf("failed to initialize clone dir: %w", err)
 	}
 
+	if local != nil {
+		logf(ctx, "Preserving git history of %s at %s\n", dir, local.Head)
+		if local.Bundle == "" {
+			// the copied .git is already a complete repository
+			return nil
+		}
+		commands := append([]string{"cd " + guestRepoDir}, local.restoreCommands()...)
+		err = updater.RunCmd(ctx, strings.Join(commands, " && "), dependabot)
+		if err != nil {
+			return fmt.Errorf("failed to restore git history: %w", err)
+		}
+		return nil
+	}
+
 	// The directory needs to be a git repo, so we need to initialize it.
 	commands := []string{
 		"cd " + guestRepoDir,
@@ -461,68 +818,45 @@ func putCloneDir(ctx context.Context, cli *client.Client, updater *Updater, dir
 	return nil
 }
 
-func pullImage(ctx context.Context, cli *client.Client, image string) error {
-	var inspect types.ImageInspect
-
+func pullImage(ctx context.Context, cli *client.Client, image string, policy PullPolicy) (types.ImageInspect, error) {
 	// check if image exists locally
+	var local *types.ImageInspect
 	inspect, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if err == nil {
+		local = &inspect
+	}
 
-	// pull image if necessary
-	if err != nil {
-		var imagePullOptions types.ImagePullOptions
-
-		if strings.HasPrefix(image, "ghcr.io/") {
-
-			token := os.Getenv("LOCAL_GITHUB_ACCESS_TOKEN")
-			if token != "" {
-				auth := base64.StdEncoding.EncodeToString([]byte("x:" + token))
-				imagePullOptions = types.ImagePullOptions{
-					RegistryAuth: fmt.Sprintf("Basic %s", auth),
-				}
-			} else {
-				log.Println("Failed to find credentials for GitHub container registry.")
-			}
-		} else if strings.Contains(image, ".azurecr.io/") {
-			username := os.Getenv("AZURE_REGISTRY_USERNAME")
-			password := os.Getenv("AZURE_REGISTRY_PASSWORD")
-
-			registryName := strings.Split(image, "/")[0]
-
-			if username != "" && password != "" {
-				authConfig := registry.AuthConfig{
-					Username:      username,
-					Password:      password,
-					ServerAddress: registryName,
-				}
-
-				encodedJSON, _ := json.Marshal(authConfig)
-				authStr := base64.URLEncoding.EncodeToString(encodedJSON)
-
-				imagePullOptions = types.ImagePullOptions{
-					RegistryAuth: authStr,
-				}
-			} else {
-				log.Println("Failed to find credentials for Azure container registry.")
-			}
-		} else {
-			log.Printf("Failed to find credentials for pulling image: %s\n.", image)
+	if !policy.shouldPull(local, time.Now()) {
+		if local == nil {
+			return inspect, fmt.Errorf("image %v isn't available locally and the pull policy is %v", image, policy.String())
 		}
+		logf(ctx, "using image %v at %s\n", image, inspect.ID)
+		return inspect, nil
+	}
 
-		log.Printf("pulling image: %s\n", image)
-		out, err := cli.ImagePull(ctx, image, imagePullOptions)
-		if err != nil {
-			return fmt.Errorf("failed to pull %v: %w", image, err)
-		}
-		_, _ = io.Copy(io.Discard, out)
-		out.Close()
+	auth, err := registryAuth(ctx, image)
+	if err != nil {
+		return inspect, err
+	}
+	imagePullOptions := types.ImagePullOptions{RegistryAuth: auth}
 
-		inspect, _, err = cli.ImageInspectWithRaw(ctx, image)
-		if err != nil {
-			return fmt.Errorf("failed to inspect %v: %w", image, err)
-		}
+	logf(ctx, "pulling image: %s\n", image)
+	out, err := cli.ImagePull(ctx, image, imagePullOptions)
+	if err != nil {
+		return inspect, fmt.Errorf("failed to pull %v: %w", image, err)
+	}
+	err = displayPullProgress(ctx, out, image)
+	out.Close()
+	if err != nil {
+		return inspect, fmt.Errorf("failed to pull %v: %w", image, err)
 	}
 
-	log.Printf("using image %v at %s\n", image, inspect.ID)
+	inspect, _, err = cli.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return inspect, fmt.Errorf("failed to inspect %v: %w", image, err)
+	}
 
-	return nil
+	logf(ctx, "using image %v at %s\n", image, inspect.ID)
+
+	return inspect, nil
 }
@@ -2,13 +2,17 @@ package infra
 
 import (
 	"context"
+	"encoding/pem"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/dependabot/cli/internal/server"
+	"github.com/docker/docker/api/types"
 
 	"github.com/dependabot/cli/internal/model"
 )
@@ -42,7 +46,7 @@ func Test_checkCredAccess(t *testing.T) {
 		credentials := []model.Credential{{
 			"token": "ghp_fake",
 		}}
-		err := checkCredAccess(context.Background(), nil, credentials)
+		err := checkCredAccess(context.Background(), nil, credentials, "")
 		if err != ErrWriteAccess {
 			t.Error("unexpected error", err)
 		}
@@ -59,11 +63,60 @@ func Test_checkCredAccess(t *testing.T) {
 		}}
 		apiEndpoint := "http://" + addr
 		job := &model.Job{Source: model.Source{APIEndpoint: &apiEndpoint}}
-		err := checkCredAccess(context.Background(), job, credentials)
+		err := checkCredAccess(context.Background(), job, credentials, "")
 		if err != ErrWriteAccess {
 			t.Error("unexpected error", err)
 		}
 	})
+
+	t.Run("it trusts a custom CA", func(t *testing.T) {
+		testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-OAuth-Scopes", "repo, write:packages")
+		}))
+		defer testServer.Close()
+
+		certPath := filepath.Join(t.TempDir(), "ca.crt")
+		cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testServer.Certificate().Raw})
+		if err := os.WriteFile(certPath, cert, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		credentials := []model.Credential{{
+			"token": "ghp_fake",
+		}}
+		job := &model.Job{Source: model.Source{APIEndpoint: &testServer.URL}}
+		if err := checkCredAccess(context.Background(), job, credentials, ""); err == nil || err == ErrWriteAccess {
+			t.Error("expected the certificate not to be trusted", err)
+		}
+		if err := checkCredAccess(context.Background(), job, credentials, certPath); err != ErrWriteAccess {
+			t.Error("unexpected error", err)
+		}
+	})
+}
+
+func Test_credAPIEndpoint(t *testing.T) {
+	hostname := "ghes.example.com"
+	apiEndpoint := "https://ghes.example.com/api/v3/"
+	ghes := &model.Job{Source: model.Source{Hostname: &hostname, APIEndpoint: &apiEndpoint}}
+
+	tests := []struct {
+		name     string
+		job      *model.Job
+		cred     model.Credential
+		expected string
+	}{
+		{"no job", nil, model.Credential{"token": "ghp_fake"}, defaultApiEndpoint},
+		{"GHES credential", ghes, model.Credential{"host": "ghes.example.com"}, apiEndpoint},
+		{"credential without a host", ghes, model.Credential{"token": "ghp_fake"}, apiEndpoint},
+		{"github.com credential", ghes, model.Credential{"host": "github.com"}, defaultApiEndpoint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := credAPIEndpoint(tt.job, tt.cred); actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
 }
 
 func Test_expandEnvironmentVariables(t *testing.T) {
@@ -95,6 +148,27 @@ func Test_expandEnvironmentVariables(t *testing.T) {
 			t.Error("expected pass NOT to be injected", api.Actual.Input.Credentials[0]["pass"])
 		}
 	})
+	t.Run("only injects the allowed variables", func(t *testing.T) {
+		os.Setenv("ENV1", "value1")
+		os.Setenv("ENV2", "value2")
+		params := &RunParams{
+			Creds: []model.Credential{{
+				"type":     "test",
+				"username": "$ENV1",
+				"pass":     "${ENV2}",
+			}},
+			CredentialEnv: []string{"ENV1"},
+		}
+
+		expandEnvironmentVariables(nil, params)
+
+		if params.Creds[0]["username"] != "value1" {
+			t.Error("expected username to be injected", params.Creds[0]["username"])
+		}
+		if params.Creds[0]["pass"] != "$ENV2" {
+			t.Error("expected pass NOT to be injected", params.Creds[0]["pass"])
+		}
+	})
 }
 
 func Test_generateIgnoreConditions(t *testing.T) {
@@ -159,3 +233,30 @@ func Test_generateIgnoreConditions(t *testing.T) {
 		}
 	})
 }
+
+func Test_imageDigest(t *testing.T) {
+	const digest = "sha256:4b1d1e5d1a7c0c3c5dcd8bd0b8f4b1f2a0e3c2d5b1a7c0c3c5dcd8bd0b8f4b1f"
+	inspect := types.ImageInspect{
+		ID: "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		RepoDigests: []string{
+			"mirror.example.com/dependabot/dependabot-updater-gomod@" + digest,
+			"ghcr.io/dependabot/dependabot-updater-gomod@" + digest,
+		},
+	}
+
+	tests := []struct {
+		image    string
+		inspect  types.ImageInspect
+		expected string
+	}{
+		{"ghcr.io/dependabot/dependabot-updater-gomod", inspect, "ghcr.io/dependabot/dependabot-updater-gomod@" + digest},
+		{"ghcr.io/dependabot/dependabot-updater-gomod:latest", inspect, "ghcr.io/dependabot/dependabot-updater-gomod@" + digest},
+		{"ghcr.io/dependabot/dependabot-updater-gomod@" + digest, inspect, "ghcr.io/dependabot/dependabot-updater-gomod@" + digest},
+		{"local-updater", types.ImageInspect{ID: inspect.ID}, inspect.ID},
+	}
+	for _, test := range tests {
+		if actual := imageDigest(test.image, test.inspect); actual != test.expected {
+			t.Errorf("for %v expected %v got %v", test.image, test.expected, actual)
+		}
+	}
+}
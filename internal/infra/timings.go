@@ -0,0 +1,68 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// phaseTimer records wall-clock durations for the phases of a run. Phases that run more than once,
+// like removing the containers during teardown, are added together.
+type phaseTimer struct {
+	phases []string
+	totals map[string]time.Duration
+}
+
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{totals: map[string]time.Duration{}}
+}
+
+// start begins timing a phase and a span of the same name. Call the returned function with the
+// result of the phase to stop both.
+func (t *phaseTimer) start(ctx context.Context, phase string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	ctx, span := startSpan(ctx, phase, attrs...)
+	started := time.Now()
+	return ctx, func(err error) {
+		t.add(phase, time.Since(started))
+		endSpan(span, err)
+	}
+}
+
+func (t *phaseTimer) add(phase string, d time.Duration) {
+	if _, ok := t.totals[phase]; !ok {
+		t.phases = append(t.phases, phase)
+	}
+	t.totals[phase] += d
+}
+
+// Timings returns the phases in the order they first started.
+func (t *phaseTimer) Timings() []model.PhaseTiming {
+	timings := make([]model.PhaseTiming, 0, len(t.phases))
+	for _, phase := range t.phases {
+		timings = append(timings, model.PhaseTiming{
+			Phase:   phase,
+			Seconds: math.Round(t.totals[phase].Seconds()*1000) / 1000,
+		})
+	}
+	return timings
+}
+
+// Print writes a human-readable report of the timings.
+func (t *phaseTimer) Print(w io.Writer) {
+	if len(t.phases) == 0 {
+		return
+	}
+	width := 0
+	for _, phase := range t.phases {
+		width = max(width, len(phase))
+	}
+	_, _ = fmt.Fprintln(w, "Phase timings:")
+	for _, phase := range t.phases {
+		_, _ = fmt.Fprintf(w, "  %-*s %10s\n", width, phase, t.totals[phase].Round(time.Millisecond))
+	}
+}
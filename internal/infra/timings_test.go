@@ -0,0 +1,38 @@
+package infra
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+func Test_phaseTimer(t *testing.T) {
+	timer := newPhaseTimer()
+	timer.add("create networks", 1500*time.Millisecond)
+	timer.add("teardown", 200*time.Millisecond)
+	timer.add("fetch_files", 3*time.Second)
+	timer.add("teardown", 300*time.Millisecond)
+
+	expected := []model.PhaseTiming{
+		{Phase: "create networks", Seconds: 1.5},
+		{Phase: "teardown", Seconds: 0.5},
+		{Phase: "fetch_files", Seconds: 3},
+	}
+	if actual := timer.Timings(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	var buf bytes.Buffer
+	timer.Print(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header and 3 phases, got %q", buf.String())
+	}
+	if !strings.Contains(lines[2], "teardown") || !strings.HasSuffix(lines[2], "500ms") {
+		t.Errorf("unexpected line: %q", lines[2])
+	}
+}
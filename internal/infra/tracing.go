@@ -0,0 +1,85 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/dependabot/cli"
+
+var (
+	tracerProvider     *sdktrace.TracerProvider
+	tracerProviderErr  error
+	tracerProviderOnce sync.Once
+)
+
+// initTracing installs the CLI's tracer provider. Spans are always sampled so the trace context handed
+// to the updater is valid, but they are only exported when OTEL_EXPORTER_OTLP_ENDPOINT (or the traces
+// specific variant) is set in the CLI's environment.
+func initTracing(ctx context.Context) error {
+	tracerProviderOnce.Do(func() {
+		opts := []sdktrace.TracerProviderOption{
+			sdktrace.WithSampler(sdktrace.AlwaysSample()),
+			sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("dependabot-cli"))),
+		}
+		if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+			exporter, err := otlptracehttp.New(ctx)
+			if err != nil {
+				tracerProviderErr = fmt.Errorf("failed to create trace exporter: %w", err)
+				return
+			}
+			opts = append(opts, sdktrace.WithBatcher(exporter))
+		}
+		tracerProvider = sdktrace.NewTracerProvider(opts...)
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	return tracerProviderErr
+}
+
+// flushTracing exports any spans that are still buffered, it's safe to call when tracing isn't initialized.
+func flushTracing(ctx context.Context) {
+	if tracerProvider != nil {
+		_ = tracerProvider.ForceFlush(ctx)
+	}
+}
+
+// startSpan starts a span for a step of the CLI's orchestration.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the error, if any, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceEnv returns the W3C trace context of ctx as environment variables, so the updater's spans
+// become children of the CLI's spans.
+func traceEnv(ctx context.Context) []string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	var env []string
+	for _, key := range carrier.Keys() {
+		env = append(env, fmt.Sprintf("%s=%s", strings.ToUpper(key), carrier.Get(key)))
+	}
+	return env
+}
@@ -0,0 +1,33 @@
+package infra
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func Test_traceEnv(t *testing.T) {
+	if err := initTracing(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("no span", func(t *testing.T) {
+		if env := traceEnv(context.Background()); len(env) != 0 {
+			t.Errorf("expected no trace environment, got %v", env)
+		}
+	})
+
+	t.Run("propagates the current span", func(t *testing.T) {
+		ctx, span := startSpan(context.Background(), "test")
+		defer span.End()
+
+		env := traceEnv(ctx)
+		if len(env) != 1 {
+			t.Fatalf("expected one variable, got %v", env)
+		}
+		traceID := span.SpanContext().TraceID().String()
+		if !strings.HasPrefix(env[0], "TRACEPARENT=00-"+traceID+"-") {
+			t.Errorf("unexpected traceparent: %v", env[0])
+		}
+	})
+}
@@ -2,7 +2,6 @@ package infra
 
 import (
 	"context"
-	"log"
 	"os"
 	gosignal "os/signal"
 	"runtime"
@@ -36,7 +35,7 @@ func resizeTtyTo(ctx context.Context, c *client.Client, id string, height, width
 	}
 
 	if err != nil {
-		log.Printf("Error resize: %s\r", err)
+		logf(ctx, "Error resize: %s\r", err)
 	}
 	return err
 }
@@ -63,7 +62,7 @@ func initTtySize(ctx context.Context, out *streams.Out, cli *client.Client, id s
 				}
 			}
 			if err != nil {
-				log.Println("failed to resize tty, using default size")
+				logf(ctx, "failed to resize tty, using default size\n")
 			}
 		}()
 	}
@@ -38,6 +38,7 @@ const (
 type Updater struct {
 	cli         *client.Client
 	containerID string
+	logs        io.Writer
 
 	// ExitCode is set once an Updater command has completed.
 	ExitCode *int
@@ -99,6 +100,7 @@ func NewUpdater(ctx context.Context, cli *client.Client, net *Networks, params *
 	updater := &Updater{
 		cli:         cli,
 		containerID: updaterContainer.ID,
+		logs:        params.logs(),
 	}
 
 	if err = putUpdaterInputs(ctx, cli, prox.ca.Cert, updaterContainer.ID, params.Job); err != nil {
@@ -251,7 +253,7 @@ func (u *Updater) RunCmd(ctx context.Context, cmd, user string, env ...string) e
 
 	r, w := io.Pipe()
 	go func() {
-		_, _ = io.Copy(os.Stderr, prefixer.New(r, "updater | "))
+		_, _ = io.Copy(u.logs, prefixer.New(r, "updater | "))
 	}()
 
 	ch := make(chan struct{})
@@ -278,6 +280,36 @@ func (u *Updater) RunCmd(ctx context.Context, cmd, user string, env ...string) e
 	return nil
 }
 
+// ReadFile copies a single file out of the updater container.
+func (u *Updater) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	r, _, err := u.cli.CopyFromContainer(ctx, u.containerID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %s from container: %w", name, err)
+	}
+	defer r.Close()
+
+	t := tar.NewReader(r)
+	if _, err = t.Next(); err != nil {
+		return nil, fmt.Errorf("failed to read %s from container: %w", name, err)
+	}
+	return io.ReadAll(t)
+}
+
+// FetchedFiles returns the dependency files the fetch_files step wrote to the output file.
+func (u *Updater) FetchedFiles(ctx context.Context) ([]model.DependencyFile, error) {
+	data, err := u.ReadFile(ctx, guestOutput)
+	if err != nil {
+		return nil, err
+	}
+	var output struct {
+		Files []model.DependencyFile `json:"base64_dependency_files"`
+	}
+	if err = json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("failed to decode fetched files: %w", err)
+	}
+	return output.Files, nil
+}
+
 // Wait blocks until the condition is true.
 func (u *Updater) Wait(ctx context.Context, condition container.WaitCondition) error {
 	wait, errCh := u.cli.ContainerWait(ctx, u.containerID, condition)
@@ -332,6 +364,33 @@ func tarball(name, contents string) (*bytes.Buffer, error) {
 	return &buf, t.Flush()
 }
 
+// tarballFile streams a tar of the file at path, named name, so a large file isn't held in memory.
+func tarballFile(name, path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, w := io.Pipe()
+	go func() {
+		defer f.Close()
+		t := tar.NewWriter(w)
+		err := t.WriteHeader(&tar.Header{Name: name, Size: info.Size(), Mode: 0644})
+		if err == nil {
+			_, err = io.Copy(t, f)
+		}
+		if err == nil {
+			err = t.Close()
+		}
+		w.CloseWithError(err)
+	}()
+	return r, nil
+}
+
 func addFileToArchive(tw *tar.Writer, name string, mode int64, content string) error {
 	header := &tar.Header{
 		Name: name,
@@ -1,11 +1,14 @@
 package infra
 
 import (
-	"github.com/dependabot/cli/internal/model"
+	"archive/tar"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/dependabot/cli/internal/model"
 )
 
 func Test_mountOptions(t *testing.T) {
@@ -69,3 +72,28 @@ func TestJobFile_ToJSON(t *testing.T) {
 		}
 	})
 }
+
+func Test_tarballFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.bundle")
+	if err := os.WriteFile(path, []byte("bundle"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := tarballFile("tmp/repo.bundle", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(tr)
+	if header.Name != "tmp/repo.bundle" || string(data) != "bundle" {
+		t.Errorf("unexpected file %s %q", header.Name, data)
+	}
+	if _, err = tr.Next(); err != io.EOF {
+		t.Errorf("expected a single file, got %v", err)
+	}
+}
@@ -1,6 +1,13 @@
 package model
 
-import "strings"
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// azureHost is the host of Azure DevOps Services, legacy visualstudio.com URLs redirect to it.
+const azureHost = "dev.azure.com"
 
 type AzureRepo struct {
 	PackageManger string
@@ -8,21 +15,104 @@ type AzureRepo struct {
 	Project       string
 	Repo          string
 	Directory     string
+	// Hostname is dev.azure.com, or the host of an Azure DevOps Server
+	Hostname string
+	// APIEndpoint is the URL the organization or collection is under, e.g. https://dev.azure.com/
+	APIEndpoint string
 }
 
 // NewAzureRepo parses a repo string and returns an AzureRepo struct
-// Expects a repo string in the format org/project/_git/repo
+// Expects a repo string in the format org/project/_git/repo, or the URL of an Azure DevOps repository like
+// https://dev.azure.com/org/project/_git/repo, https://org.visualstudio.com/project/_git/repo, or
+// https://server/tfs/collection/project/_git/repo for Azure DevOps Server.
 func NewAzureRepo(packageManager string, repo string, directory string) *AzureRepo {
-	repoParts := strings.Split(repo, "/")
-	if len(repoParts) != 4 {
+	azureRepo := &AzureRepo{
+		PackageManger: packageManager,
+		Directory:     directory,
+		Hostname:      azureHost,
+		APIEndpoint:   "https://" + azureHost + "/",
+	}
+
+	path := repo
+	host := ""
+	// Azure DevOps Server is often on plain http, the other schemes are remotes of an https server
+	scheme := "https"
+	if strings.Contains(repo, "://") {
+		u, err := url.Parse(repo)
+		if err != nil || u.Host == "" {
+			return nil
+		}
+		host, path = u.Host, u.Path
+		if strings.EqualFold(u.Scheme, "http") {
+			scheme = "http"
+		}
+	} else if first, rest, ok := strings.Cut(repo, "/"); ok && strings.Contains(first, ".") {
+		// a URL without the scheme, org/project/_git/repo never has a dot in the organization
+		host, path = first, rest
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	gitIndex := -1
+	for i, part := range parts {
+		if part == "_git" {
+			gitIndex = i
+			break
+		}
+	}
+	if gitIndex < 1 || gitIndex != len(parts)-2 {
 		return nil
 	}
+	azureRepo.Project = parts[gitIndex-1]
+	azureRepo.Repo = parts[gitIndex+1]
+	prefix := parts[:gitIndex-1]
 
-	return &AzureRepo{
-		PackageManger: packageManager,
-		Org:           repoParts[0],
-		Project:       repoParts[1],
-		Repo:          repoParts[3],
-		Directory:     directory,
+	switch {
+	case host == "" || strings.EqualFold(host, azureHost):
+		if len(prefix) != 1 {
+			return nil
+		}
+		azureRepo.Org = prefix[0]
+	case strings.HasSuffix(strings.ToLower(host), ".visualstudio.com"):
+		// the organization is the subdomain, the path may still have the old DefaultCollection in it
+		if len(prefix) > 1 {
+			return nil
+		}
+		azureRepo.Org = strings.Split(host, ".")[0]
+	default:
+		// Azure DevOps Server has the collection in place of the organization, under an optional
+		// virtual directory like /tfs
+		if len(prefix) == 0 {
+			return nil
+		}
+		azureRepo.Org = prefix[len(prefix)-1]
+		azureRepo.Hostname = host
+		azureRepo.APIEndpoint = fmt.Sprintf("%s://%s/", scheme, strings.Join(append([]string{host}, prefix[:len(prefix)-1]...), "/"))
+	}
+	if azureRepo.Org == "" || azureRepo.Project == "" || azureRepo.Repo == "" {
+		return nil
+	}
+	return azureRepo
+}
+
+// Path is the repository in the format the updater expects, org/project/_git/repo.
+func (r *AzureRepo) Path() string {
+	return fmt.Sprintf("%s/%s/_git/%s", r.Org, r.Project, r.Repo)
+}
+
+// IsServer is whether the repository is on an Azure DevOps Server rather than dev.azure.com.
+func (r *AzureRepo) IsServer() bool {
+	return r.Hostname != azureHost
+}
+
+// CredentialHosts are the hosts the repository and the organization's artifact feeds are on.
+func (r *AzureRepo) CredentialHosts() []string {
+	if r.IsServer() {
+		// feeds are served by the server too
+		return []string{(&url.URL{Host: r.Hostname}).Hostname()}
+	}
+	return []string{
+		azureHost,
+		fmt.Sprintf("%s.pkgs.visualstudio.com", r.Org),
+		"pkgs." + azureHost,
 	}
 }
@@ -24,8 +24,99 @@ func Test_NewAzureRepo(t *testing.T) {
 				Project:       "my-project",
 				Repo:          "my-repo",
 				Directory:     "/",
+				Hostname:      "dev.azure.com",
+				APIEndpoint:   "https://dev.azure.com/",
 			},
 		},
+		{
+			name:           "dev.azure.com URL",
+			packageManager: "npm_and_yarn",
+			repo:           "https://my-org@dev.azure.com/my-org/my-project/_git/my-repo",
+			directory:      "/",
+			expected: &AzureRepo{
+				PackageManger: "npm_and_yarn",
+				Org:           "my-org",
+				Project:       "my-project",
+				Repo:          "my-repo",
+				Directory:     "/",
+				Hostname:      "dev.azure.com",
+				APIEndpoint:   "https://dev.azure.com/",
+			},
+		},
+		{
+			name:           "URL without a scheme",
+			packageManager: "npm_and_yarn",
+			repo:           "dev.azure.com/my-org/my-project/_git/my-repo",
+			directory:      "/",
+			expected: &AzureRepo{
+				PackageManger: "npm_and_yarn",
+				Org:           "my-org",
+				Project:       "my-project",
+				Repo:          "my-repo",
+				Directory:     "/",
+				Hostname:      "dev.azure.com",
+				APIEndpoint:   "https://dev.azure.com/",
+			},
+		},
+		{
+			name:           "visualstudio.com URL",
+			packageManager: "nuget",
+			repo:           "https://my-org.visualstudio.com/DefaultCollection/my-project/_git/my-repo",
+			directory:      "/src",
+			expected: &AzureRepo{
+				PackageManger: "nuget",
+				Org:           "my-org",
+				Project:       "my-project",
+				Repo:          "my-repo",
+				Directory:     "/src",
+				Hostname:      "dev.azure.com",
+				APIEndpoint:   "https://dev.azure.com/",
+			},
+		},
+		{
+			name:           "Azure DevOps Server URL",
+			packageManager: "nuget",
+			repo:           "https://tfs.example.com:8080/tfs/DefaultCollection/my-project/_git/my-repo",
+			directory:      "/",
+			expected: &AzureRepo{
+				PackageManger: "nuget",
+				Org:           "DefaultCollection",
+				Project:       "my-project",
+				Repo:          "my-repo",
+				Directory:     "/",
+				Hostname:      "tfs.example.com:8080",
+				APIEndpoint:   "https://tfs.example.com:8080/tfs/",
+			},
+		},
+		{
+			name:           "Azure DevOps Server over http",
+			packageManager: "nuget",
+			repo:           "http://tfs:8080/tfs/Coll/my-project/_git/my-repo",
+			directory:      "/",
+			expected: &AzureRepo{
+				PackageManger: "nuget",
+				Org:           "Coll",
+				Project:       "my-project",
+				Repo:          "my-repo",
+				Directory:     "/",
+				Hostname:      "tfs:8080",
+				APIEndpoint:   "http://tfs:8080/tfs/",
+			},
+		},
+		{
+			name:           "URL without a repository",
+			packageManager: "npm_and_yarn",
+			repo:           "https://dev.azure.com/my-org/my-project",
+			directory:      "/",
+			expected:       nil,
+		},
+		{
+			name:           "GitHub repo",
+			packageManager: "npm_and_yarn",
+			repo:           "dependabot/cli",
+			directory:      "/",
+			expected:       nil,
+		},
 		{
 			name:           "invalid repo",
 			packageManager: "npm_and_yarn",
@@ -44,3 +135,13 @@ func Test_NewAzureRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestAzureRepo_CredentialHosts(t *testing.T) {
+	server := NewAzureRepo("nuget", "https://tfs.example.com:8080/tfs/DefaultCollection/my-project/_git/my-repo", "/")
+	if hosts := server.CredentialHosts(); !reflect.DeepEqual(hosts, []string{"tfs.example.com"}) {
+		t.Errorf("unexpected hosts %v", hosts)
+	}
+	if server.Path() != "DefaultCollection/my-project/_git/my-repo" {
+		t.Errorf("unexpected path %v", server.Path())
+	}
+}
@@ -0,0 +1,9 @@
+package model
+
+// Images are the container images a job ran with, by digest where the registry provided one,
+// so the job can be reproduced with the same images later.
+type Images struct {
+	Updater   string `json:"updater,omitempty" yaml:"updater,omitempty"`
+	Proxy     string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	Collector string `json:"collector,omitempty" yaml:"collector,omitempty"`
+}
@@ -22,6 +22,9 @@ Finally, if you need to add a key for experimental features, please ensure:
 
 This will avoid churn where the experimental key makes it into other, unrelated smoke-tests as they are updated for other
 reasons.
+
+When core starts calling a new API endpoint or reading a new job field, add it to the compatibility table in
+internal/infra/compatibility.go, so users with an older CLI are warned instead of getting decode errors.
 */
 
 // Job is the data that is passed to the updater.
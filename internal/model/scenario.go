@@ -6,6 +6,14 @@ type Scenario struct {
 	Input Input `yaml:"input"`
 	// Output is the list of expected outputs
 	Output []Output `yaml:"output,omitempty"`
+	// Timings is how long each phase of the run that produced the scenario took
+	Timings []PhaseTiming `yaml:"timings,omitempty"`
+}
+
+// PhaseTiming is the wall-clock duration of one phase of a run, e.g. pulling images or fetch_files.
+type PhaseTiming struct {
+	Phase   string  `json:"phase" yaml:"phase"`
+	Seconds float64 `json:"seconds" yaml:"seconds"`
 }
 
 // Input is the input to a job
@@ -14,6 +22,8 @@ type Input struct {
 	Job Job `yaml:"job"`
 	// Credentials is the registry info and tokens to pass to the Proxy
 	Credentials []Credential `yaml:"credentials,omitempty"`
+	// Images are the resolved images the job ran with
+	Images *Images `yaml:"images,omitempty"`
 }
 
 // Output is the expected output given the inputs
@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 17, 30, 0, time.UTC) // a Friday
+	tests := []struct {
+		spec string
+		want []time.Time
+	}{
+		{"@every 6h", []time.Time{start.Add(6 * time.Hour), start.Add(12 * time.Hour)}},
+		{"@daily", []time.Time{
+			time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC),
+		}},
+		{"@hourly", []time.Time{
+			time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		}},
+		{"*/15 * * * *", []time.Time{
+			time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+			time.Date(2024, 3, 1, 10, 45, 0, 0, time.UTC),
+		}},
+		{"30 2 * * 1-5", []time.Time{
+			time.Date(2024, 3, 4, 2, 30, 0, 0, time.UTC),
+			time.Date(2024, 3, 5, 2, 30, 0, 0, time.UTC),
+		}},
+		{"0 9 * * 7", []time.Time{
+			time.Date(2024, 3, 3, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC),
+		}},
+		{"0 0 29 2 *", []time.Time{
+			time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		}},
+		// both day fields restricted matches either of them
+		{"0 0 15 * 1", []time.Time{
+			time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		}},
+		{"0 0 30 2 *", []time.Time{{}}},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		next := start
+		for _, want := range tt.want {
+			next = schedule.Next(next)
+			if !next.Equal(want) {
+				t.Errorf("%q: expected %v, got %v", tt.spec, want, next)
+				break
+			}
+		}
+	}
+}
+
+func TestParseSchedule_invalid(t *testing.T) {
+	for _, spec := range []string{"", "@every 1s", "@every soon", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@sometimes"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("expected %q to be invalid", spec)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package queue
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// maxInputSize limits the size of a submitted job.
+const maxInputSize = 10 << 20
+
+// inputTypes are the content types a job can be submitted as.
+var inputTypes = map[string]bool{
+	"application/json":   true,
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// Authorize only lets requests with the bearer token through to h. Requests from browsers on behalf
+// of other sites are rejected too, since a job's credentials are sent to the hosts it names.
+func Authorize(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" || (r.Header.Get("Sec-Fetch-Site") != "" && r.Header.Get("Sec-Fetch-Site") != "none") {
+			http.Error(w, "cross-origin requests aren't allowed", http.StatusForbidden)
+			return
+		}
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ServeHTTP implements the HTTP API of the queue:
+//
+//	POST /jobs?name={name}  submits a job, the body is the input in JSON or YAML, the name is optional
+//	GET  /jobs              lists the jobs
+//	GET  /jobs/{id}         returns the status of a job
+//	GET  /jobs/{id}/logs    returns the logs of a job, which grow while it runs
+//	GET  /jobs/{id}/output  returns the scenario recorded by a finished job
+func (q *Queue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "jobs" || len(parts) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, q.List())
+		case http.MethodPost:
+			q.submit(w, r)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	job, ok := q.Get(parts[1])
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	if len(parts) == 2 {
+		writeJSON(w, http.StatusOK, job)
+		return
+	}
+
+	switch parts[2] {
+	case "logs":
+		q.serveFile(w, job.ID, LogsFile, "text/plain; charset=utf-8")
+	case "output":
+		if job.Status == Queued || job.Status == Running {
+			http.Error(w, "the job hasn't finished", http.StatusNotFound)
+			return
+		}
+		q.serveFile(w, job.ID, OutputFile, "application/yaml")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (q *Queue) submit(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); !inputTypes[mediaType] {
+		http.Error(w, "the input must be application/json or application/yaml", http.StatusUnsupportedMediaType)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxInputSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var input model.Input
+	if err = json.Unmarshal(data, &input); err != nil {
+		if err = yaml.Unmarshal(data, &input); err != nil {
+			http.Error(w, "failed to decode input: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if input.Job.PackageManager == "" {
+		http.Error(w, "the job requires a package-manager", http.StatusBadRequest)
+		return
+	}
+
+	job, err := q.Submit(r.URL.Query().Get("name"), &input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (q *Queue) serveFile(w http.ResponseWriter, id, name, contentType string) {
+	f, err := os.Open(q.Path(id, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		http.Error(w, name+" isn't available yet", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", contentType)
+	_, _ = io.Copy(w, f)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
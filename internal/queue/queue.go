@@ -0,0 +1,327 @@
+// Package queue runs Dependabot jobs in the background, keeping the input, logs, and recorded
+// scenario of each job on disk.
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	Queued    Status = "queued"
+	Running   Status = "running"
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+)
+
+// Files in the directory of each job.
+const (
+	JobFile    = "job.json"
+	InputFile  = "input.yml"
+	LogsFile   = "logs.txt"
+	OutputFile = "output.yml"
+)
+
+// Job is a job submitted to the queue.
+type Job struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name,omitempty"`
+	Status     Status     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created-at"`
+	StartedAt  *time.Time `json:"started-at,omitempty"`
+	FinishedAt *time.Time `json:"finished-at,omitempty"`
+}
+
+// RunFunc runs the input, writing the output of the containers to logs and the scenario to output.
+type RunFunc func(input *model.Input, logs io.Writer, output string) error
+
+// Queue runs the submitted jobs in order, at most concurrency at a time.
+type Queue struct {
+	dir string
+	run RunFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	jobs    map[string]*Job
+	pending []string
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// New creates a queue storing jobs in dir. Jobs left in dir by a previous queue are loaded: queued
+// jobs are run, and jobs that were running are marked as failed.
+func New(dir string, concurrency int, run RunFunc) (*Queue, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1, got %d", concurrency)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+
+	q := &Queue{dir: dir, run: run, jobs: map[string]*Job{}}
+	q.cond = sync.NewCond(&q.mu)
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	for i := 0; i < concurrency; i++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+	return q, nil
+}
+
+func (q *Queue) load() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read jobs directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(q.dir, entry.Name(), JobFile))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var job Job
+		if err = json.Unmarshal(data, &job); err != nil {
+			log.Printf("Skipping job %s: %v\n", entry.Name(), err)
+			continue
+		}
+		switch job.Status {
+		case Queued:
+			q.pending = append(q.pending, job.ID)
+		case Running:
+			job.Status = Failed
+			job.Error = "interrupted"
+			if err = q.save(&job); err != nil {
+				return err
+			}
+		}
+		q.jobs[job.ID] = &job
+	}
+	sort.Slice(q.pending, func(i, j int) bool {
+		return q.jobs[q.pending[i]].CreatedAt.Before(q.jobs[q.pending[j]].CreatedAt)
+	})
+	return nil
+}
+
+// Submit stores the input and queues it. The name is optional, and groups runs of the same job.
+func (q *Queue) Submit(name string, input *model.Input) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := &Job{ID: id, Name: name, Status: Queued, CreatedAt: time.Now().UTC()}
+
+	if err = os.Mkdir(q.Path(id, ""), 0700); err != nil {
+		return Job{}, fmt.Errorf("failed to create job directory: %w", err)
+	}
+	data, err := yaml.Marshal(input)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to marshal input: %w", err)
+	}
+	// the input can contain credentials
+	if err = os.WriteFile(q.Path(id, InputFile), data, 0600); err != nil {
+		return Job{}, fmt.Errorf("failed to write input: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return Job{}, fmt.Errorf("the queue is closed")
+	}
+	if err = q.save(job); err != nil {
+		return Job{}, err
+	}
+	q.jobs[id] = job
+	q.pending = append(q.pending, id)
+	q.cond.Signal()
+	return *job, nil
+}
+
+// Get returns the job with the ID.
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Wait blocks until the job has finished, or the queue is closed, and returns it.
+func (q *Queue) Wait(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	for (job.Status == Queued || job.Status == Running) && !q.closed {
+		q.cond.Wait()
+	}
+	return *job, true
+}
+
+// Prune deletes the finished jobs with the name, except for the newest keep.
+func (q *Queue) Prune(name string, keep int) error {
+	var finished []Job
+	for _, job := range q.List() {
+		if job.Name == name && job.Status != Queued && job.Status != Running {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= keep {
+		return nil
+	}
+	for _, job := range finished[:len(finished)-keep] {
+		q.mu.Lock()
+		delete(q.jobs, job.ID)
+		q.mu.Unlock()
+		if err := os.RemoveAll(q.Path(job.ID, "")); err != nil {
+			return fmt.Errorf("failed to delete job %s: %w", job.ID, err)
+		}
+	}
+	return nil
+}
+
+// List returns every job, oldest first.
+func (q *Queue) List() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Path returns the path of a file in the job's directory.
+func (q *Queue) Path(id, name string) string {
+	return filepath.Join(q.dir, id, name)
+}
+
+// Close stops starting new jobs and waits for the running ones to finish. Queued jobs stay on disk
+// and are run by the next queue using the directory.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	q.workers.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.workers.Done()
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		job := q.jobs[q.pending[0]]
+		q.pending = q.pending[1:]
+		now := time.Now().UTC()
+		job.Status = Running
+		job.StartedAt = &now
+		err := q.save(job)
+		q.mu.Unlock()
+
+		if err == nil {
+			err = q.execute(job.ID)
+		}
+
+		q.mu.Lock()
+		now = time.Now().UTC()
+		job.FinishedAt = &now
+		job.Status = Succeeded
+		if err != nil {
+			job.Status = Failed
+			job.Error = err.Error()
+		}
+		if err = q.save(job); err != nil {
+			log.Printf("Failed to save job %s: %v\n", job.ID, err)
+		}
+		// wakes up Wait as well as idle workers
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	}
+}
+
+func (q *Queue) execute(id string) (err error) {
+	data, err := os.ReadFile(q.Path(id, InputFile))
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	var input model.Input
+	if err = yaml.Unmarshal(data, &input); err != nil {
+		return fmt.Errorf("failed to decode input: %w", err)
+	}
+
+	logs, err := os.Create(q.Path(id, LogsFile))
+	if err != nil {
+		return fmt.Errorf("failed to create logs: %w", err)
+	}
+	defer logs.Close()
+
+	// a panic in one job shouldn't stop the others
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	log.Printf("Running job %s\n", id)
+	return q.run(&input, logs, q.Path(id, OutputFile))
+}
+
+// save writes the job file, the caller must hold the lock.
+func (q *Queue) save(job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := q.Path(job.ID, JobFile+".tmp")
+	if err = os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	if err = os.Rename(tmp, q.Path(job.ID, JobFile)); err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// newID returns an ID starting with the time, e.g. 20240301T120000-1a2b3c, so directories are easy to find
+func newID() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,235 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+)
+
+// waitFor polls the job until it's finished.
+func waitFor(t *testing.T, q *Queue, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, _ := q.Get(id); job.Status == Succeeded || job.Status == Failed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s didn't finish", id)
+	return Job{}
+}
+
+func TestQueue(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var ran []string
+	q, err := New(dir, 1, func(input *model.Input, logs io.Writer, output string) error {
+		mu.Lock()
+		ran = append(ran, input.Job.PackageManager)
+		mu.Unlock()
+		fmt.Fprintln(logs, "updating", input.Job.Source.Repo)
+		if input.Job.PackageManager == "fail" {
+			return errors.New("updater failure")
+		}
+		return os.WriteFile(output, []byte("output: []\n"), 0600)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	ok, err := q.Submit("", &model.Input{Job: model.Job{PackageManager: "go_modules", Source: model.Source{Repo: "rsc/quote"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := q.Submit("", &model.Input{Job: model.Job{PackageManager: "fail"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job := waitFor(t, q, ok.ID); job.Status != Succeeded || job.StartedAt == nil || job.FinishedAt == nil {
+		t.Errorf("unexpected job %+v", job)
+	}
+	if job := waitFor(t, q, failed.ID); job.Status != Failed || job.Error != "updater failure" {
+		t.Errorf("unexpected job %+v", job)
+	}
+	if strings.Join(ran, ",") != "go_modules,fail" {
+		t.Errorf("expected the jobs to run in order, got %v", ran)
+	}
+	logs, err := os.ReadFile(q.Path(ok.ID, LogsFile))
+	if err != nil || string(logs) != "updating rsc/quote\n" {
+		t.Errorf("unexpected logs %q: %v", logs, err)
+	}
+	if jobs := q.List(); len(jobs) != 2 || jobs[0].ID != ok.ID {
+		t.Errorf("unexpected jobs %+v", jobs)
+	}
+}
+
+func TestQueue_load(t *testing.T) {
+	dir := t.TempDir()
+	block := make(chan struct{})
+	q, err := New(dir, 1, func(input *model.Input, logs io.Writer, output string) error {
+		<-block
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, _ := q.Submit("", &model.Input{Job: model.Job{PackageManager: "go_modules"}})
+	queued, _ := q.Submit("", &model.Input{Job: model.Job{PackageManager: "npm_and_yarn"}})
+	for {
+		if job, _ := q.Get(running.ID); job.Status == Running {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// simulate the process being killed while a job is running
+	q.mu.Lock()
+	data, _ := json.Marshal(q.jobs[running.ID])
+	// stop the worker picking up the queued job
+	q.closed = true
+	q.mu.Unlock()
+	close(block)
+	q.Close()
+	if err = os.WriteFile(q.Path(running.ID, JobFile), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var ran []string
+	q, err = New(dir, 1, func(input *model.Input, logs io.Writer, output string) error {
+		ran = append(ran, input.Job.PackageManager)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	if job := waitFor(t, q, queued.ID); job.Status != Succeeded {
+		t.Errorf("expected the queued job to run, got %+v", job)
+	}
+	if job, _ := q.Get(running.ID); job.Status != Failed || job.Error != "interrupted" {
+		t.Errorf("expected the running job to be interrupted, got %+v", job)
+	}
+	if len(ran) != 1 || ran[0] != "npm_and_yarn" {
+		t.Errorf("unexpected jobs ran %v", ran)
+	}
+}
+
+func TestQueue_ServeHTTP(t *testing.T) {
+	q, err := New(t.TempDir(), 2, func(input *model.Input, logs io.Writer, output string) error {
+		fmt.Fprintln(logs, "done")
+		return os.WriteFile(output, []byte("input:\n  job:\n    package-manager: go_modules\n"), 0600)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	srv := httptest.NewServer(q)
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	resp, err := http.Post(srv.URL+"/jobs", "application/yaml", strings.NewReader("job:\n  package-manager: go_modules\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job Job
+	_ = json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || job.ID == "" || resp.Header.Get("Location") != "/jobs/"+job.ID {
+		t.Fatalf("unexpected response %v %+v", resp.StatusCode, job)
+	}
+
+	waitFor(t, q, job.ID)
+	if status, body := get("/jobs/" + job.ID); status != http.StatusOK || !strings.Contains(body, `"status":"succeeded"`) {
+		t.Errorf("unexpected status %v %v", status, body)
+	}
+	if status, body := get("/jobs/" + job.ID + "/logs"); status != http.StatusOK || body != "done\n" {
+		t.Errorf("unexpected logs %v %v", status, body)
+	}
+	if status, body := get("/jobs/" + job.ID + "/output"); status != http.StatusOK || !strings.Contains(body, "go_modules") {
+		t.Errorf("unexpected output %v %v", status, body)
+	}
+	if status, body := get("/jobs"); status != http.StatusOK || !strings.Contains(body, job.ID) {
+		t.Errorf("unexpected list %v %v", status, body)
+	}
+
+	if status, _ := get("/jobs/unknown"); status != http.StatusNotFound {
+		t.Errorf("expected not found, got %v", status)
+	}
+	if status, _ := get("/other"); status != http.StatusNotFound {
+		t.Errorf("expected not found, got %v", status)
+	}
+	resp, err = http.Post(srv.URL+"/jobs", "application/json", strings.NewReader(`{"job": {}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a job without a package manager to be rejected, got %v", resp.StatusCode)
+	}
+	resp, err = http.Post(srv.URL+"/jobs", "text/plain", strings.NewReader("job:\n  package-manager: go_modules\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("expected a text/plain job to be rejected, got %v", resp.StatusCode)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	srv := httptest.NewServer(Authorize("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"with the token", map[string]string{"Authorization": "Bearer secret"}, http.StatusNoContent},
+		{"without a token", nil, http.StatusUnauthorized},
+		{"with a wrong token", map[string]string{"Authorization": "Bearer other"}, http.StatusUnauthorized},
+		{"from another site", map[string]string{"Authorization": "Bearer secret", "Origin": "https://example.com"}, http.StatusForbidden},
+		{"from a browser", map[string]string{"Authorization": "Bearer secret", "Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/jobs", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("expected %v, got %v", tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// SummaryFile is written to the queue's directory by the Scheduler.
+const SummaryFile = "summary.yml"
+
+// ScheduledJob is a job the Scheduler submits to the queue on a schedule.
+type ScheduledJob struct {
+	Name string
+	// Spec is the schedule as written in the config, for the summary
+	Spec     string
+	Schedule Schedule
+	Input    *model.Input
+	// Keep is how many finished runs of the job are kept
+	Keep int
+}
+
+// Scheduler submits jobs to a queue when they're due, and keeps a summary of their recent runs.
+type Scheduler struct {
+	queue *Queue
+	jobs  []ScheduledJob
+
+	mu     sync.Mutex
+	next   map[string]time.Time
+	active map[string]bool
+}
+
+func NewScheduler(q *Queue, jobs []ScheduledJob) *Scheduler {
+	return &Scheduler{
+		queue:  q,
+		jobs:   jobs,
+		next:   map[string]time.Time{},
+		active: map[string]bool{},
+	}
+}
+
+// Run submits the jobs when they're due until ctx is done. With runNow, every job is also submitted
+// straight away.
+func (s *Scheduler) Run(ctx context.Context, runNow bool) {
+	now := time.Now()
+	s.mu.Lock()
+	for _, job := range s.jobs {
+		s.next[job.Name] = job.Schedule.Next(now)
+	}
+	s.mu.Unlock()
+	if runNow {
+		for _, job := range s.jobs {
+			s.submit(job)
+		}
+	}
+	s.writeSummary()
+
+	for {
+		var due time.Time
+		s.mu.Lock()
+		for _, next := range s.next {
+			if !next.IsZero() && (due.IsZero() || next.Before(due)) {
+				due = next
+			}
+		}
+		s.mu.Unlock()
+		if due.IsZero() {
+			log.Println("None of the jobs are scheduled to run again")
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now = time.Now()
+		for _, job := range s.jobs {
+			s.mu.Lock()
+			next := s.next[job.Name]
+			isDue := !next.IsZero() && !next.After(now)
+			if isDue {
+				s.next[job.Name] = job.Schedule.Next(now)
+			}
+			s.mu.Unlock()
+			if isDue {
+				s.submit(job)
+			}
+		}
+		s.writeSummary()
+	}
+}
+
+// submit queues a run of the job, unless the previous one hasn't finished.
+func (s *Scheduler) submit(job ScheduledJob) {
+	s.mu.Lock()
+	if s.active[job.Name] {
+		s.mu.Unlock()
+		log.Printf("Skipping %s, the previous run hasn't finished\n", job.Name)
+		return
+	}
+	s.active[job.Name] = true
+	s.mu.Unlock()
+
+	queued, err := s.queue.Submit(job.Name, job.Input)
+	if err != nil {
+		log.Printf("Failed to submit %s: %v\n", job.Name, err)
+		s.mu.Lock()
+		s.active[job.Name] = false
+		s.mu.Unlock()
+		return
+	}
+	log.Printf("Submitted %s as job %s\n", job.Name, queued.ID)
+
+	go func() {
+		finished, _ := s.queue.Wait(queued.ID)
+		if finished.Status == Succeeded || finished.Status == Failed {
+			log.Printf("Job %s of %s %s\n", finished.ID, job.Name, finished.Status)
+		}
+		s.mu.Lock()
+		s.active[job.Name] = false
+		s.mu.Unlock()
+		if err := s.queue.Prune(job.Name, job.Keep); err != nil {
+			log.Println(err)
+		}
+		s.writeSummary()
+	}()
+}
+
+// Summary is the content of the summary file.
+type Summary struct {
+	UpdatedAt time.Time    `yaml:"updated-at"`
+	Jobs      []JobSummary `yaml:"jobs"`
+}
+
+// JobSummary lists the recent runs of a scheduled job, newest first.
+type JobSummary struct {
+	Name     string       `yaml:"name"`
+	Schedule string       `yaml:"schedule"`
+	NextRun  *time.Time   `yaml:"next-run,omitempty"`
+	Runs     []RunSummary `yaml:"runs"`
+}
+
+type RunSummary struct {
+	ID         string     `yaml:"id"`
+	Status     Status     `yaml:"status"`
+	Error      string     `yaml:"error,omitempty"`
+	StartedAt  *time.Time `yaml:"started-at,omitempty"`
+	FinishedAt *time.Time `yaml:"finished-at,omitempty"`
+	Duration   string     `yaml:"duration,omitempty"`
+	// PullRequests counts the pull requests the run created, updated, and closed
+	PullRequests map[string]int `yaml:"pull-requests,omitempty"`
+}
+
+func (s *Scheduler) summary() Summary {
+	summary := Summary{UpdatedAt: time.Now().UTC()}
+	jobs := s.queue.List()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, scheduled := range s.jobs {
+		js := JobSummary{Name: scheduled.Name, Schedule: scheduled.Spec, Runs: []RunSummary{}}
+		if next := s.next[scheduled.Name]; !next.IsZero() {
+			js.NextRun = &next
+		}
+		for i := len(jobs) - 1; i >= 0; i-- {
+			if jobs[i].Name == scheduled.Name {
+				js.Runs = append(js.Runs, s.runSummary(jobs[i]))
+			}
+		}
+		summary.Jobs = append(summary.Jobs, js)
+	}
+	return summary
+}
+
+func (s *Scheduler) runSummary(job Job) RunSummary {
+	run := RunSummary{
+		ID:         job.ID,
+		Status:     job.Status,
+		Error:      job.Error,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.StartedAt != nil && job.FinishedAt != nil {
+		run.Duration = job.FinishedAt.Sub(*job.StartedAt).Round(time.Second).String()
+	}
+	if job.Status == Succeeded {
+		run.PullRequests = countPullRequests(s.queue.Path(job.ID, OutputFile))
+	}
+	return run
+}
+
+// countPullRequests counts the pull request calls in a recorded scenario.
+func countPullRequests(filename string) map[string]int {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	var scenario struct {
+		Output []struct {
+			Type string `yaml:"type"`
+		} `yaml:"output"`
+	}
+	if err = yaml.Unmarshal(data, &scenario); err != nil {
+		return nil
+	}
+	counts := map[string]int{}
+	for _, out := range scenario.Output {
+		switch out.Type {
+		case "create_pull_request":
+			counts["created"]++
+		case "update_pull_request":
+			counts["updated"]++
+		case "close_pull_request":
+			counts["closed"]++
+		}
+	}
+	return counts
+}
+
+func (s *Scheduler) writeSummary() {
+	data, err := yaml.Marshal(s.summary())
+	if err != nil {
+		log.Printf("Failed to write summary: %v\n", err)
+		return
+	}
+	filename := filepath.Join(s.queue.dir, SummaryFile)
+	tmp := fmt.Sprintf("%s.%d.tmp", filename, time.Now().UnixNano())
+	if err = os.WriteFile(tmp, data, 0600); err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		log.Printf("Failed to write summary: %v\n", err)
+	}
+}
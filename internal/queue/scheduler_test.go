@@ -0,0 +1,83 @@
+package queue
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+func TestScheduler(t *testing.T) {
+	dir := t.TempDir()
+	runs := make(chan string, 10)
+	q, err := New(dir, 1, func(input *model.Input, logs io.Writer, output string) error {
+		runs <- input.Job.PackageManager
+		return os.WriteFile(output, []byte("output:\n  - type: create_pull_request\n  - type: close_pull_request\n  - type: mark_as_processed\n"), 0600)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	daily, _ := ParseSchedule("@daily")
+	jobs := []ScheduledJob{{
+		Name:     "quote",
+		Spec:     "@daily",
+		Schedule: daily,
+		Input:    &model.Input{Job: model.Job{PackageManager: "go_modules"}},
+		Keep:     1,
+	}}
+
+	// the old run is pruned when the new one finishes
+	old, _ := q.Submit("quote", jobs[0].Input)
+	if job, _ := q.Wait(old.ID); job.Status != Succeeded {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	<-runs
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewScheduler(q, jobs).Run(ctx, true)
+		close(done)
+	}()
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the job to run now")
+	}
+
+	var summary Summary
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, _ := os.ReadFile(filepath.Join(dir, SummaryFile))
+		summary = Summary{}
+		_ = yaml.Unmarshal(data, &summary)
+		if len(summary.Jobs) == 1 && len(summary.Jobs[0].Runs) == 1 && summary.Jobs[0].Runs[0].Status == Succeeded {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if len(summary.Jobs) != 1 || len(summary.Jobs[0].Runs) != 1 {
+		t.Fatalf("expected one kept run in the summary, got %+v", summary)
+	}
+	js := summary.Jobs[0]
+	if js.Name != "quote" || js.Schedule != "@daily" || js.NextRun == nil {
+		t.Errorf("unexpected summary %+v", js)
+	}
+	run := js.Runs[0]
+	if run.ID == old.ID || run.PullRequests["created"] != 1 || run.PullRequests["closed"] != 1 {
+		t.Errorf("unexpected run %+v", run)
+	}
+	if _, err := os.Stat(q.Path(old.ID, "")); !os.IsNotExist(err) {
+		t.Errorf("expected the old run to be deleted")
+	}
+}
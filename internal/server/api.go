@@ -28,14 +28,23 @@ type API struct {
 	Expectations []model.Output
 	// Errors is the error list populated by doing a Dependabot run
 	Errors []error
+	// Warnings are problems with the calls that don't fail the run, like calls that don't match the API spec
+	Warnings []error
+	// UpstreamErrors are the calls the job API of ForwardTo rejected or couldn't be sent to, which
+	// aren't a failed expectation since rejecting calls is up to the upstream
+	UpstreamErrors []error
+	// StrictSpec makes calls that don't match the API spec errors instead of warnings
+	StrictSpec bool
 	// Actual will contain the scenario output that actually happened after the run is Complete
 	Actual model.Scenario
 
 	server          *http.Server
+	contents        map[string]string
 	cursor          int
 	hasExpectations bool
 	port            int
 	writer          io.Writer
+	forwarder       *forwarder
 }
 
 // NewAPI creates a new API instance and starts the server
@@ -64,6 +73,7 @@ func NewAPI(expected []model.Output, writer io.Writer) *API {
 	}
 	api := &API{
 		server:          server,
+		contents:        map[string]string{},
 		Expectations:    expected,
 		writer:          writer,
 		cursor:          0,
@@ -120,9 +130,25 @@ func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	actual, err := decodeWrapper(kind, data)
 	if err != nil {
 		a.pushError(err)
+	} else if err = validateRequest(kind, data); err != nil {
+		if a.StrictSpec {
+			a.pushError(err)
+		} else {
+			a.pushWarning(err)
+		}
 	}
+	a.storeContents(kind, data)
 
-	if actual == nil {
+	if a.forwarder != nil {
+		// the upstream's response goes back to the updater, the call is still recorded below
+		if err := a.forwarder.forward(w, r, data); err != nil {
+			log.Println(err)
+			a.UpstreamErrors = append(a.UpstreamErrors, err)
+		}
+		if actual == nil {
+			return
+		}
+	} else if actual == nil {
 		// indicates the kind (endpoint) isn't implemented in decodeWrapper, so return a 501
 		w.WriteHeader(http.StatusNotImplemented)
 		return
@@ -147,6 +173,46 @@ func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	a.assertExpectation(kind, actual)
 }
 
+// storeContents keeps the content of files that decodeWrapper replaces with a hash, so it can be
+// recovered with OriginalFile.
+func (a *API) storeContents(kind string, data []byte) {
+	if a.contents == nil {
+		a.contents = map[string]string{}
+	}
+	collectContents(a.contents, kind, data)
+}
+
+// collectContents adds the base64 encoded files of a pull request call to contents, keyed by their hash.
+func collectContents(contents map[string]string, kind string, data []byte) {
+	var files []model.DependencyFile
+	switch kind {
+	case "create_pull_request":
+		pr, _ := decode[model.CreatePullRequest](data)
+		files = pr.UpdatedDependencyFiles
+	case "update_pull_request":
+		pr, _ := decode[model.UpdatePullRequest](data)
+		files = pr.UpdatedDependencyFiles
+	}
+	for _, file := range files {
+		if file.ContentEncoding == "base64" {
+			contents[hashContent(file.Content)] = file.Content
+		}
+	}
+}
+
+// OriginalFile returns the file as the updater sent it, undoing replaceBinaryWithHash when the
+// content was received by this API.
+func (a *API) OriginalFile(file model.DependencyFile) model.DependencyFile {
+	if file.ContentEncoding != "sha256" {
+		return file
+	}
+	if content, ok := a.contents[file.Content]; ok {
+		file.Content = content
+		file.ContentEncoding = "base64"
+	}
+	return file
+}
+
 func (a *API) assertExpectation(kind string, actual *model.UpdateWrapper) {
 	if len(a.Expectations) <= a.cursor {
 		err := fmt.Errorf("missing expectation")
@@ -194,6 +260,14 @@ func (a *API) pushError(err error) {
 	a.Errors = append(a.Errors, err)
 }
 
+// pushWarning logs a problem with a call without failing the run.
+func (a *API) pushWarning(err error) {
+	escapedWarning := strings.ReplaceAll(err.Error(), "\n", "")
+	escapedWarning = strings.ReplaceAll(escapedWarning, "\r", "")
+	log.Println("Warning:", escapedWarning)
+	a.Warnings = append(a.Warnings, err)
+}
+
 func (a *API) pushResult(kind string, actual *model.UpdateWrapper) error {
 	// TODO validate required data
 	output := model.Output{
@@ -210,6 +284,19 @@ func (a *API) pushResult(kind string, actual *model.UpdateWrapper) error {
 	return nil
 }
 
+// Endpoints are the update job API endpoints modeled by the fake API, each is handled by decodeWrapper.
+var Endpoints = []string{
+	"update_dependency_list",
+	"create_pull_request",
+	"update_pull_request",
+	"close_pull_request",
+	"mark_as_processed",
+	"record_ecosystem_versions",
+	"record_update_job_error",
+	"record_update_job_unknown_error",
+	"increment_metric",
+}
+
 func decodeWrapper(kind string, data []byte) (actual *model.UpdateWrapper, err error) {
 	actual = &model.UpdateWrapper{}
 	switch kind {
@@ -251,13 +338,17 @@ func replaceBinaryWithHash(files []model.DependencyFile) []model.DependencyFile
 			// since this is also called for the expected value, this needs to not be base64
 			// otherwise it will calculate the checksum of the checksum
 			file.ContentEncoding = "sha256"
-			hash := sha256.Sum256([]byte(file.Content))
-			file.Content = hex.EncodeToString(hash[:])
+			file.Content = hashContent(file.Content)
 		}
 	}
 	return files
 }
 
+func hashContent(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
 func decode[T any](data []byte) (T, error) {
 	var wrapper struct {
 		Data T `json:"data" yaml:"data"`
@@ -3,7 +3,10 @@ package server
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/dependabot/cli/internal/model"
 )
 
 func Test_decodeWrapper(t *testing.T) {
@@ -15,6 +18,14 @@ func Test_decodeWrapper(t *testing.T) {
 	})
 }
 
+func Test_Endpoints(t *testing.T) {
+	for _, endpoint := range Endpoints {
+		if actual, _ := decodeWrapper(endpoint, []byte(`data: {}`)); actual == nil {
+			t.Errorf("endpoint %v isn't handled by decodeWrapper", endpoint)
+		}
+	}
+}
+
 func TestAPI_ServeHTTP(t *testing.T) {
 	t.Run("doesn't crash when unknown endpoint is used", func(t *testing.T) {
 		request := httptest.NewRequest("POST", "/unexpected-endpoint", nil)
@@ -28,3 +39,81 @@ func TestAPI_ServeHTTP(t *testing.T) {
 		}
 	})
 }
+
+func TestAPI_OriginalFile(t *testing.T) {
+	body := `{"data":{"base-commit-sha":"abc","dependencies":[],"updated-dependency-files":[{"name":"go.sum","directory":"/","content":"aGVsbG8=","content_encoding":"base64"}]}}`
+	request := httptest.NewRequest("POST", "/update_jobs/cli/create_pull_request", strings.NewReader(body))
+	response := httptest.NewRecorder()
+
+	api := NewAPI(nil, nil)
+	defer api.Stop()
+	api.ServeHTTP(response, request)
+
+	pr := api.Actual.Output[0].Expect.Data.(model.CreatePullRequest)
+	file := pr.UpdatedDependencyFiles[0]
+	if file.ContentEncoding != "sha256" {
+		t.Fatalf("expected the content to be hashed, got %v", file.ContentEncoding)
+	}
+	original := api.OriginalFile(file)
+	if original.ContentEncoding != "base64" || original.Content != "aGVsbG8=" {
+		t.Errorf("expected the original content, got %v %v", original.ContentEncoding, original.Content)
+	}
+}
+
+func TestAPI_ForwardTo(t *testing.T) {
+	var path, auth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		if strings.HasSuffix(r.URL.Path, "/record_ecosystem_versions") {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	api := NewAPI(nil, nil)
+	defer api.Stop()
+	if err := api.ForwardTo(upstream.URL+"/api/", "token"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("returns the upstream response and records the call", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/update_jobs/1/mark_as_processed", strings.NewReader(`{"data":{"base-commit-sha":"abc"}}`))
+		response := httptest.NewRecorder()
+		api.ServeHTTP(response, request)
+
+		if response.Code != http.StatusOK || response.Body.String() != `{"ok":true}` {
+			t.Errorf("unexpected response %d %s", response.Code, response.Body.String())
+		}
+		if path != "/api/update_jobs/1/mark_as_processed" || auth != "token" {
+			t.Errorf("unexpected upstream request %s %s", path, auth)
+		}
+		if len(api.Actual.Output) != 1 || api.Actual.Output[0].Type != "mark_as_processed" {
+			t.Errorf("expected the call to be recorded, got %+v", api.Actual.Output)
+		}
+	})
+
+	t.Run("passes on upstream errors", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/update_jobs/1/record_ecosystem_versions", strings.NewReader(`{"data":{}}`))
+		response := httptest.NewRecorder()
+		api.ServeHTTP(response, request)
+
+		if response.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, response.Code)
+		}
+		if len(api.UpstreamErrors) != 1 {
+			t.Errorf("expected an upstream error, got %v", api.UpstreamErrors)
+		}
+		if len(api.Errors) != 0 {
+			t.Errorf("expected the upstream error not to fail the run, got %v", api.Errors)
+		}
+	})
+
+	t.Run("rejects invalid URLs", func(t *testing.T) {
+		if err := NewAPI(nil, nil).ForwardTo("localhost", ""); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
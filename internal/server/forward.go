@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// forwarder sends the calls the API receives on to a real job API.
+type forwarder struct {
+	upstream *url.URL
+	token    string
+	client   *http.Client
+}
+
+// ForwardTo makes the API send each call on to the upstream job API and reply with its response, while
+// still recording and asserting the call. The token, if set, replaces the updater's Authorization header.
+// It must be called before the updater starts.
+func (a *API) ForwardTo(upstream string, token string) error {
+	u, err := url.Parse(upstream)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid upstream URL %q", upstream)
+	}
+	a.forwarder = &forwarder{
+		upstream: u,
+		token:    token,
+		// less than the server's WriteTimeout so the updater gets an error response
+		client: &http.Client{Timeout: 9 * time.Second},
+	}
+	return nil
+}
+
+// forward sends the request to the upstream and copies the response to w.
+func (f *forwarder) forward(w http.ResponseWriter, r *http.Request, body []byte) error {
+	target := *f.upstream
+	target.Path = strings.TrimSuffix(f.upstream.Path, "/") + r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return fmt.Errorf("failed to forward %s: %w", r.URL.Path, err)
+	}
+	req.Header = r.Header.Clone()
+	if f.token != "" {
+		req.Header.Set("Authorization", f.token)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return fmt.Errorf("failed to forward %s: %w", r.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("upstream responded to %s with %s", r.URL.Path, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Replayer sends recorded calls to a job API the way the updater made them.
+type Replayer struct {
+	// URL is the base URL of the job API, the calls go to <URL>/update_jobs/<JobID>/<type>
+	URL   string
+	JobID string
+	// Token is sent as the Authorization header, like the updater's job token
+	Token string
+	// Original is called for files whose content was replaced with a hash, see FilesFrom
+	Original func(model.DependencyFile) model.DependencyFile
+
+	client *http.Client
+}
+
+// ReplayResult is the response to a replayed call.
+type ReplayResult struct {
+	Type   string
+	Status int
+	Body   []byte
+	// Hashed is the number of files sent as a hash because the original content wasn't available
+	Hashed int
+	Err    error
+}
+
+// OK is whether the job API accepted the call.
+func (r ReplayResult) OK() bool {
+	return r.Err == nil && r.Status < 300
+}
+
+// Send sends a recorded output to the job API.
+func (r *Replayer) Send(ctx context.Context, output model.Output) ReplayResult {
+	result := ReplayResult{Type: output.Type}
+	body, hashed, err := r.encode(output)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Hashed = hashed
+
+	url := fmt.Sprintf("%s/update_jobs/%s/%s", strings.TrimSuffix(r.URL, "/"), r.JobID, output.Type)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dependabot-cli")
+	if r.Token != "" {
+		req.Header.Set("Authorization", r.Token)
+	}
+
+	if r.client == nil {
+		r.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	result.Status = resp.StatusCode
+	result.Body, result.Err = io.ReadAll(resp.Body)
+	return result
+}
+
+// encode returns the JSON body of the call, with the original content of hashed files where it's known.
+func (r *Replayer) encode(output model.Output) ([]byte, int, error) {
+	// scenario files decode the data as a map, a round trip gives it the type of the call
+	data, err := yaml.Marshal(output.Expect)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to encode %s: %w", output.Type, err)
+	}
+	contents := map[string]string{}
+	collectContents(contents, output.Type, data)
+	actual, err := decodeWrapper(output.Type, data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var files []model.DependencyFile
+	switch pr := actual.Data.(type) {
+	case model.CreatePullRequest:
+		files = pr.UpdatedDependencyFiles
+	case model.UpdatePullRequest:
+		files = pr.UpdatedDependencyFiles
+	}
+	hashed := 0
+	for i := range files {
+		file := &files[i]
+		if file.ContentEncoding != "sha256" {
+			continue
+		}
+		if content, ok := contents[file.Content]; ok {
+			file.Content, file.ContentEncoding = content, "base64"
+		} else if r.Original != nil {
+			*file = r.Original(*file)
+		}
+		if file.ContentEncoding == "sha256" {
+			hashed++
+		}
+	}
+
+	body, err := json.Marshal(actual)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to encode %s: %w", output.Type, err)
+	}
+	return body, hashed, nil
+}
+
+// FilesFrom returns a function that restores hashed files from copies in the directories, laid out like
+// the repository (e.g. a checkout with the pull request applied), when a copy matches the hash.
+func FilesFrom(dirs []string) func(model.DependencyFile) model.DependencyFile {
+	return func(file model.DependencyFile) model.DependencyFile {
+		if file.ContentEncoding != "sha256" {
+			return file
+		}
+		for _, dir := range dirs {
+			data, err := os.ReadFile(filepath.Join(dir, file.Directory, file.Name))
+			if err != nil {
+				continue
+			}
+			// the updater may have sent the content wrapped at 60 characters, like Ruby's Base64.encode64
+			strict := base64.StdEncoding.EncodeToString(data)
+			for _, content := range []string{strict, wrapBase64(strict)} {
+				if hashContent(content) == file.Content {
+					file.Content, file.ContentEncoding = content, "base64"
+					return file
+				}
+			}
+		}
+		return file
+	}
+}
+
+func wrapBase64(encoded string) string {
+	var b strings.Builder
+	for len(encoded) > 60 {
+		b.WriteString(encoded[:60])
+		b.WriteString("\n")
+		encoded = encoded[60:]
+	}
+	if encoded != "" {
+		b.WriteString(encoded)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+func TestReplayer_Send(t *testing.T) {
+	type request struct {
+		path, auth string
+		body       map[string]any
+	}
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		req := request{path: r.URL.Path, auth: r.Header.Get("Authorization")}
+		_ = json.Unmarshal(data, &req.body)
+		requests = append(requests, req)
+		if strings.HasSuffix(r.URL.Path, "/record_update_job_error") {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"error":"unknown error type"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// a scenario as it's read from a file, with the file content hashed
+	var scenario model.Scenario
+	err := yaml.Unmarshal([]byte(`
+output:
+  - type: create_pull_request
+    expect:
+      data:
+        base-commit-sha: abc
+        dependencies: []
+        updated-dependency-files:
+          - name: go.sum
+            directory: /
+            content: `+hashContent(base64.StdEncoding.EncodeToString([]byte("hello\n")))+`
+            content_encoding: sha256
+          - name: go.mod
+            directory: /
+            content: `+hashContent("missing")+`
+            content_encoding: sha256
+  - type: record_update_job_error
+    expect:
+      data:
+        error-type: oops
+        error-details: {}
+`), &scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, "go.sum"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	replayer := &Replayer{URL: srv.URL + "/", JobID: "123", Token: "token", Original: FilesFrom([]string{dir})}
+
+	result := replayer.Send(context.Background(), scenario.Output[0])
+	if !result.OK() || result.Status != http.StatusNoContent || result.Hashed != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	result = replayer.Send(context.Background(), scenario.Output[1])
+	if result.OK() || result.Status != http.StatusUnprocessableEntity || !strings.Contains(string(result.Body), "unknown error type") {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	if requests[0].path != "/update_jobs/123/create_pull_request" || requests[0].auth != "token" {
+		t.Errorf("unexpected request %s %s", requests[0].path, requests[0].auth)
+	}
+	files := requests[0].body["data"].(map[string]any)["updated-dependency-files"].([]any)
+	restored := files[0].(map[string]any)
+	if restored["content_encoding"] != "base64" || restored["content"] != base64.StdEncoding.EncodeToString([]byte("hello\n")) {
+		t.Errorf("expected the original content, got %v", restored)
+	}
+	if files[1].(map[string]any)["content_encoding"] != "sha256" {
+		t.Errorf("expected the unknown file to stay hashed, got %v", files[1])
+	}
+	if requests[1].body["data"].(map[string]any)["error-type"] != "oops" {
+		t.Errorf("unexpected body %v", requests[1].body)
+	}
+}
+
+func TestFilesFrom(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(strings.Repeat("0123456789", 10))
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "file.bin"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped := wrapBase64(base64.StdEncoding.EncodeToString(data))
+	file := FilesFrom([]string{t.TempDir(), dir})(model.DependencyFile{
+		Name: "file.bin", Directory: "/sub", Content: hashContent(wrapped), ContentEncoding: "sha256",
+	})
+	if file.ContentEncoding != "base64" || file.Content != wrapped {
+		t.Errorf("expected the wrapped content to be restored, got %v %q", file.ContentEncoding, file.Content)
+	}
+
+	changed := FilesFrom([]string{dir})(model.DependencyFile{
+		Name: "file.bin", Directory: "/sub", Content: hashContent("other"), ContentEncoding: "sha256",
+	})
+	if changed.ContentEncoding != "sha256" {
+		t.Error("expected a file with different content not to be restored")
+	}
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OpenAPI is an OpenAPI 3 document describing the update job API.
+type OpenAPI struct {
+	OpenAPI    string                `json:"openapi" yaml:"openapi"`
+	Info       SpecInfo              `json:"info" yaml:"info"`
+	Paths      map[string]*PathItem  `json:"paths" yaml:"paths"`
+	Components Components            `json:"components" yaml:"components"`
+	Security   []map[string][]string `json:"security" yaml:"security"`
+}
+
+type SpecInfo struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	Version     string `json:"version" yaml:"version"`
+}
+
+type PathItem struct {
+	Post *Operation `json:"post" yaml:"post"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId" yaml:"operationId"`
+	Parameters  []Parameter          `json:"parameters" yaml:"parameters"`
+	RequestBody RequestBody          `json:"requestBody" yaml:"requestBody"`
+	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name" yaml:"name"`
+	In       string  `json:"in" yaml:"in"`
+	Required bool    `json:"required" yaml:"required"`
+	Schema   *Schema `json:"schema" yaml:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required" yaml:"required"`
+	Content  map[string]*MediaType `json:"content" yaml:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema" yaml:"schema"`
+}
+
+type Response struct {
+	Description string `json:"description" yaml:"description"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas" yaml:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes" yaml:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type" yaml:"type"`
+	In          string `json:"in" yaml:"in"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// Schema is the subset of an OpenAPI 3.0 schema object needed to describe the model types.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty" yaml:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+const schemaRefPrefix = "#/components/schemas/"
+
+// Spec generates the OpenAPI document from the endpoints and the types decodeWrapper decodes them into.
+// Like the fake API, it rejects unknown properties but allows any property to be missing or null.
+func Spec(version string) *OpenAPI {
+	spec := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info: SpecInfo{
+			Title:       "Dependabot update job API",
+			Description: "The calls the Dependabot updater makes while running an update job.",
+			Version:     version,
+		},
+		Paths: map[string]*PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{
+				"jobToken": {
+					Type:        "apiKey",
+					In:          "header",
+					Name:        "Authorization",
+					Description: "The job token given to the updater as DEPENDABOT_JOB_TOKEN.",
+				},
+			},
+		},
+		Security: []map[string][]string{{"jobToken": {}}},
+	}
+
+	for _, endpoint := range Endpoints {
+		actual, _ := decodeWrapper(endpoint, []byte(`data: {}`))
+		data := spec.schemaOf(reflect.TypeOf(actual.Data))
+		spec.Paths["/update_jobs/{id}/"+endpoint] = &PathItem{Post: &Operation{
+			OperationID: endpoint,
+			Parameters: []Parameter{
+				{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+			},
+			RequestBody: RequestBody{
+				Required: true,
+				Content: map[string]*MediaType{"application/json": {Schema: &Schema{
+					Type:                 "object",
+					Properties:           map[string]*Schema{"data": data},
+					Required:             []string{"data"},
+					AdditionalProperties: new(bool),
+				}}},
+			},
+			Responses: map[string]*Response{"2XX": {Description: "The call was accepted."}},
+		}}
+	}
+	return spec
+}
+
+// schemaOf returns the schema of t, adding structs to the components so they're only described once.
+func (s *OpenAPI) schemaOf(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schemaOf(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		if _, ok := s.Components.Schemas[t.Name()]; !ok {
+			schema := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: new(bool)}
+			// added before the fields in case the type refers to itself
+			s.Components.Schemas[t.Name()] = schema
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				name := fieldName(field)
+				if !field.IsExported() || name == "" {
+					continue
+				}
+				property := s.schemaOf(field.Type)
+				if property.Ref != "" {
+					property = &Schema{AllOf: []*Schema{property}}
+				}
+				property.Nullable = true
+				schema.Properties[name] = property
+			}
+		}
+		return &Schema{Ref: schemaRefPrefix + t.Name()}
+	default:
+		// interfaces can hold anything
+		return &Schema{}
+	}
+}
+
+// fieldName is the key the fake API decodes the field from, which follows yaml.v3's rules.
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+var apiSpec = sync.OnceValue(func() *OpenAPI { return Spec("") })
+
+// validateRequest checks the body of a call to the endpoint against the spec.
+func validateRequest(endpoint string, body []byte) error {
+	path, ok := apiSpec().Paths["/update_jobs/{id}/"+endpoint]
+	if !ok {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s request isn't JSON: %w", endpoint, err)
+	}
+	var problems []string
+	apiSpec().validate(path.Post.RequestBody.Content["application/json"].Schema, value, "", &problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("%s request doesn't match the API spec: %s", endpoint, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (s *OpenAPI) validate(schema *Schema, value any, path string, problems *[]string) {
+	if value == nil {
+		// the decoder treats null as the zero value, so everything is nullable
+		return
+	}
+	if schema.Ref != "" {
+		s.validate(s.Components.Schemas[strings.TrimPrefix(schema.Ref, schemaRefPrefix)], value, path, problems)
+		return
+	}
+	for _, sub := range schema.AllOf {
+		s.validate(sub, value, path, problems)
+	}
+
+	fail := func(path, format string, args ...any) {
+		if path == "" {
+			path = "body"
+		}
+		*problems = append(*problems, strings.TrimPrefix(path, ".")+": "+fmt.Sprintf(format, args...))
+	}
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			fail(path, "expected an object, got %s", jsonType(value))
+			return
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := schema.Properties[key]
+			switch {
+			case ok:
+				s.validate(property, object[key], path+"."+key, problems)
+			case schema.AdditionalProperties != nil && !*schema.AdditionalProperties:
+				fail(path+"."+key, "unknown property")
+			}
+		}
+		for _, key := range schema.Required {
+			if _, ok := object[key]; !ok {
+				fail(path+"."+key, "missing property")
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			fail(path, "expected an array, got %s", jsonType(value))
+			return
+		}
+		for i, item := range array {
+			s.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "string", "boolean", "number":
+		if got := jsonType(value); got != schema.Type {
+			fail(path, "expected a %s, got %s", schema.Type, got)
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			fail(path, "expected an integer, got %s", jsonType(value))
+		}
+	}
+}
+
+// jsonType names the type of a value decoded by encoding/json.
+func jsonType(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return "null"
+}
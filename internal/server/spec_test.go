@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dependabot/cli/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSpec(t *testing.T) {
+	spec := Spec("1.0.0")
+	if len(spec.Paths) != len(Endpoints) {
+		t.Errorf("expected a path for each of the %d endpoints, got %d", len(Endpoints), len(spec.Paths))
+	}
+	for _, endpoint := range Endpoints {
+		if _, ok := spec.Paths["/update_jobs/{id}/"+endpoint]; !ok {
+			t.Errorf("endpoint %v has no path", endpoint)
+		}
+	}
+
+	// every reference must resolve
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range strings.Split(string(data), `"$ref":"`)[1:] {
+		ref, _, _ := strings.Cut(part, `"`)
+		if _, ok := spec.Components.Schemas[strings.TrimPrefix(ref, schemaRefPrefix)]; !ok {
+			t.Errorf("unresolved reference %v", ref)
+		}
+	}
+
+	dependencyFile := spec.Components.Schemas["DependencyFile"]
+	if dependencyFile == nil || dependencyFile.Properties["content_encoding"].Type != "string" {
+		t.Errorf("unexpected DependencyFile schema %+v", dependencyFile)
+	}
+}
+
+func Test_validateRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		body     string
+		problem  string
+	}{
+		{"valid", "mark_as_processed", `{"data":{"base-commit-sha":"abc"}}`, ""},
+		{"null", "create_pull_request", `{"data":{"pr-body":null,"dependency-group":null,"dependencies":[{"name":"a","version":null}]}}`, ""},
+		{"wrong type", "mark_as_processed", `{"data":{"base-commit-sha":1}}`, "data.base-commit-sha: expected a string, got number"},
+		{"unknown property", "close_pull_request", `{"data":{"reason":"up_to_date","other":true}}`, "data.other: unknown property"},
+		{"nested", "update_dependency_list", `{"data":{"dependencies":[{"name":"a","requirements":[{"file":false}]}]}}`, "data.dependencies[0].requirements[0].file: expected a string, got boolean"},
+		{"missing data", "mark_as_processed", `{}`, "data: missing property"},
+		{"not an object", "mark_as_processed", `[]`, "body: expected an object, got array"},
+		{"unknown endpoint", "unexpected", `[]`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRequest(tt.endpoint, []byte(tt.body))
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+				t.Errorf("expected %q, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+// The recorded scenarios are real updater calls, so they must match the spec.
+func Test_validateRequest_scenarios(t *testing.T) {
+	files, _ := filepath.Glob("../../testdata/go/*.yaml")
+	if len(files) == 0 {
+		t.Fatal("no scenarios found")
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var scenario model.Scenario
+		if err = yaml.Unmarshal(data, &scenario); err != nil {
+			t.Fatal(err)
+		}
+		for i, output := range scenario.Output {
+			body, err := json.Marshal(output.Expect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = validateRequest(output.Type, body); err != nil {
+				t.Errorf("%s output %d: %v", filepath.Base(file), i, err)
+			}
+		}
+	}
+}
+
+func TestAPI_ServeHTTP_validates(t *testing.T) {
+	request := httptest.NewRequest("POST", "/update_jobs/cli/mark_as_processed", strings.NewReader(`{"data":{"base-commit-sha":1}}`))
+	response := httptest.NewRecorder()
+
+	api := NewAPI(nil, nil)
+	defer api.Stop()
+	api.ServeHTTP(response, request)
+
+	if len(api.Errors) != 0 {
+		t.Errorf("expected no errors, got %v", api.Errors)
+	}
+	if len(api.Warnings) != 1 || !strings.Contains(api.Warnings[0].Error(), "doesn't match the API spec") {
+		t.Errorf("expected a validation warning, got %v", api.Warnings)
+	}
+
+	t.Run("fails the run when strict", func(t *testing.T) {
+		request := httptest.NewRequest("POST", "/update_jobs/cli/mark_as_processed", strings.NewReader(`{"data":{"base-commit-sha":1}}`))
+		api := NewAPI(nil, nil)
+		defer api.Stop()
+		api.StrictSpec = true
+		api.ServeHTTP(httptest.NewRecorder(), request)
+
+		if len(api.Errors) != 1 || !strings.Contains(api.Errors[0].Error(), "doesn't match the API spec") {
+			t.Errorf("expected a validation error, got %v", api.Errors)
+		}
+	})
+}